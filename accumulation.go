@@ -0,0 +1,190 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"m-box_explore/render"
+)
+
+const (
+	// accumulationTextureUnit and the unit after it hold the new frame and
+	// the history it is blended into
+	accumulationTextureUnit = 7
+	// Past this many frames the image has converged, so the scene stops
+	// being redrawn and the accumulated result is shown as is
+	maxAccumulatedFrames = 256
+)
+
+// Progressive accumulation settings. While the view holds still, each frame
+// is rendered with a sub-pixel jitter and averaged into the previous ones.
+// jitter is the offset in pixels for the frame being drawn.
+var (
+	accumulate = true
+	jitter     mgl32.Vec2
+)
+
+var (
+	accumulationShaderSource = `
+		#version 330 core
+		in vec2 uv;
+		out vec4 FragColor;
+		uniform sampler2D current;
+		uniform sampler2D history;
+		uniform int accumulationCount; // frames already in history
+		void main() {
+			vec4 color = texture(current, uv);
+			vec4 previous = texture(history, uv);
+			FragColor = mix(previous, color, 1.0 / float(accumulationCount + 1));
+		}
+	` + "\x00"
+)
+
+// viewState is what the accumulated image depends on beyond the keyboard,
+// which resets the average on its own; a change in any of it starts the
+// average over.
+type viewState struct {
+	position, front, up mgl32.Vec3
+	width, height       int32
+	fov                 float32
+	scale               float32
+	maxIterations       int32
+	fractalType         int32
+	juliaMode           bool
+	juliaC              mgl32.Vec3
+	animationTime       float64
+	debugOffset         mgl32.Vec3
+	debugZoom           float32
+}
+
+// accumulator averages successive jittered renders of a still view into a
+// floating-point history buffer, ping-ponging between two of them.
+type accumulator struct {
+	program *render.Program
+	quad    *render.Quad
+	copier  *render.Copy
+
+	sceneFBO        uint32
+	sceneTexture    uint32
+	historyFBOs     [2]uint32
+	historyTextures [2]uint32
+	current         int // index of the history target holding the average
+	width           int32
+	height          int32
+
+	count int
+	last  viewState
+}
+
+func newAccumulator(quad *render.Quad, copier *render.Copy) (*accumulator, error) {
+	program, err := render.NewProgram(render.QuadVertexShader, accumulationShaderSource)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &accumulator{program: program, quad: quad, copier: copier}
+	a.sceneFBO, a.sceneTexture = newColorTarget()
+	for i := range a.historyFBOs {
+		a.historyFBOs[i], a.historyTextures[i] = newColorTarget()
+	}
+	return a, nil
+}
+
+func (a *accumulator) resize(w, h int32) {
+	if w == a.width && h == a.height {
+		return
+	}
+	a.width, a.height = w, h
+
+	gl.BindTexture(gl.TEXTURE_2D, a.sceneTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, w, h, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	for _, texture := range a.historyTextures {
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, w, h, 0, gl.RGBA, gl.FLOAT, nil)
+	}
+}
+
+// reset discards the accumulated frames.
+func (a *accumulator) reset() {
+	a.count = 0
+}
+
+// converged reports whether further frames would leave the image as it is.
+func (a *accumulator) converged() bool {
+	return !accumulate || a.count >= maxAccumulatedFrames
+}
+
+// render draws the scene with this frame's jitter, folds it into the
+// running average and copies the average onto the framebuffer that was
+// bound beforehand. The average restarts whenever the view changes.
+func (a *accumulator) render(cam *camera.Camera, drawScene func()) {
+	a.resize(fbWidth, fbHeight)
+
+	state := viewState{
+		position:      cam.Position,
+		front:         cam.Front,
+		up:            cam.Up,
+		width:         fbWidth,
+		height:        fbHeight,
+		fov:           fov,
+		scale:         scale,
+		maxIterations: maxIterations,
+		fractalType:   fractalType,
+		juliaMode:     juliaMode,
+		juliaC:        juliaC,
+		animationTime: animationTime,
+		debugOffset:   debugOffset,
+		debugZoom:     debugZoom,
+	}
+	if state != a.last {
+		a.reset()
+		a.last = state
+	}
+
+	var target int32
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &target)
+
+	if a.count < maxAccumulatedFrames {
+		// The first frame is unjittered so a moving view stays steady
+		if a.count > 0 {
+			jitter = mgl32.Vec2{halton(a.count, 2) - 0.5, halton(a.count, 3) - 0.5}
+		}
+		gl.BindFramebuffer(gl.FRAMEBUFFER, a.sceneFBO)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		drawScene()
+		jitter = mgl32.Vec2{}
+
+		next := 1 - a.current
+		gl.BindFramebuffer(gl.FRAMEBUFFER, a.historyFBOs[next])
+		gl.Viewport(0, 0, fbWidth, fbHeight)
+		a.program.Use()
+		gl.ActiveTexture(gl.TEXTURE0 + accumulationTextureUnit)
+		gl.BindTexture(gl.TEXTURE_2D, a.sceneTexture)
+		gl.ActiveTexture(gl.TEXTURE0 + accumulationTextureUnit + 1)
+		gl.BindTexture(gl.TEXTURE_2D, a.historyTextures[a.current])
+		gl.ActiveTexture(gl.TEXTURE0)
+		gl.Uniform1i(a.program.Uniform("current"), accumulationTextureUnit)
+		gl.Uniform1i(a.program.Uniform("history"), accumulationTextureUnit+1)
+		gl.Uniform1i(a.program.Uniform("accumulationCount"), int32(a.count))
+		a.quad.Draw()
+
+		a.current = next
+		a.count++
+	}
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(target))
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+	a.copier.Draw(a.historyTextures[a.current])
+}
+
+// halton returns the i'th element of the Halton sequence in the given base,
+// a well spread set of points in [0, 1).
+func halton(i int, base int) float32 {
+	f, r := float32(1), float32(0)
+	for i > 0 {
+		f /= float32(base)
+		r += f * float32(i%base)
+		i /= base
+	}
+	return r
+}
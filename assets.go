@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"log"
+)
+
+// assetLoader reads and decodes asset files on goroutines, so startup
+// doesn't wait on them, and hands each one's GL upload back to the main
+// thread, which owns the context.
+type assetLoader struct {
+	uploads chan func()
+	pending int // loads started whose upload hasn't run; main thread only
+
+	// sources delivers the fractal shaders once they're read
+	sources chan shaderSources
+}
+
+// loadAssets starts reading the fractal shaders and loading the palette and
+// cubemap given on the command line. Until the palette and cubemap arrive
+// the default palette and the sky are used.
+func loadAssets() *assetLoader {
+	l := &assetLoader{
+		uploads: make(chan func(), 2),
+		sources: make(chan shaderSources, 1),
+	}
+
+	// The program can't be built without them, so they're waited for in
+	// shaders rather than uploaded as they arrive
+	go func() {
+		l.sources <- readShaders()
+	}()
+
+	if palettePath != "" {
+		l.load("palette", func() (func(), error) {
+			stops, err := loadPalette(palettePath)
+			if err != nil {
+				return nil, err
+			}
+			return func() {
+				gl.DeleteTextures(1, &paletteTexture)
+				paletteTexture = uploadPalette(stops)
+			}, nil
+		})
+	}
+
+	if cubemapDir != "" {
+		l.load("cubemap", func() (func(), error) {
+			faces, err := readCubemap(cubemapPaths(cubemapDir))
+			if err != nil {
+				return nil, err
+			}
+			return func() {
+				uploadCubemap(faces)
+			}, nil
+		})
+	}
+	return l
+}
+
+// load runs read on its own goroutine. read must not call GL: the upload
+// function it returns does that, on the main thread in poll or wait. A
+// failed load is logged and leaves the defaults in place.
+func (l *assetLoader) load(name string, read func() (func(), error)) {
+	l.pending++
+	go func() {
+		upload, err := read()
+		if err != nil {
+			upload = func() {
+				log.Printf("failed to load %s: %v\n", name, err)
+			}
+		}
+		l.uploads <- upload
+	}()
+}
+
+// poll runs the uploads that are ready without waiting for the rest, and
+// reports whether there were any.
+func (l *assetLoader) poll() bool {
+	ran := false
+	for l.pending > 0 {
+		select {
+		case upload := <-l.uploads:
+			upload()
+			l.pending--
+			ran = true
+		default:
+			return ran
+		}
+	}
+	return ran
+}
+
+// wait blocks until every load has been uploaded.
+func (l *assetLoader) wait() {
+	for ; l.pending > 0; l.pending-- {
+		upload := <-l.uploads
+		upload()
+	}
+}
+
+// shaders blocks until the fractal shaders have been read and returns them.
+// It may be called once.
+func (l *assetLoader) shaders() shaderSources {
+	return <-l.sources
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"m-box_explore/render"
+	"math"
+	"sort"
+)
+
+const (
+	benchmarkFrames = 600
+	benchmarkRadius = 3.5 // distance from the origin the camera circles at
+)
+
+// benchmark is set with -benchmark.
+var benchmark bool
+
+// runBenchmark flies the camera once around a fixed circle, timing each
+// frame on the GPU, and prints frame time statistics. The path and frame
+// count never change, so runs with the same flags are comparable.
+func runBenchmark(window *glfw.Window, renderer *render.Renderer, cam *camera.Camera) {
+	glfw.SwapInterval(0)
+
+	var query uint32
+	gl.GenQueries(1, &query)
+	defer gl.DeleteQueries(1, &query)
+
+	times := make([]float64, 0, benchmarkFrames)
+	for i := 0; i < benchmarkFrames && !window.ShouldClose(); i++ {
+		benchmarkCamera(cam, float64(i)/benchmarkFrames)
+
+		gl.BeginQuery(gl.TIME_ELAPSED, query)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		renderer.Draw(sceneParams(cam))
+		gl.EndQuery(gl.TIME_ELAPSED)
+
+		window.SwapBuffers()
+		glfw.PollEvents()
+
+		// Blocks until the frame finishes, which is fine when benchmarking
+		var elapsed uint64
+		gl.GetQueryObjectui64v(query, gl.QUERY_RESULT, &elapsed)
+		times = append(times, float64(elapsed)/1e6)
+	}
+
+	printFrameStats(times)
+}
+
+// benchmarkCamera places the camera at fraction f of the way around the
+// benchmark circle, looking at the origin and bobbing up and down.
+func benchmarkCamera(cam *camera.Camera, f float64) {
+	cam.Velocity = mgl32.Vec3{}
+	cam.Yaw = float32(360 * f)
+	cam.Pitch = float32(20 * math.Sin(2*math.Pi*f))
+	cam.Roll = 0
+	cam.UpdateFront()
+	cam.Orbit(mgl32.Vec3{}, benchmarkRadius)
+}
+
+// printFrameStats reports the spread of frame times, in milliseconds.
+func printFrameStats(times []float64) {
+	if len(times) == 0 {
+		fmt.Println("benchmark: no frames rendered")
+		return
+	}
+
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+
+	var total float64
+	for _, t := range sorted {
+		total += t
+	}
+	percentile := func(p float64) float64 {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+
+	fmt.Printf("benchmark: %d frames at %dx%d\n", len(sorted), fbWidth, fbHeight)
+	fmt.Printf("  min %.2f ms  avg %.2f ms  max %.2f ms\n", sorted[0], total/float64(len(sorted)), sorted[len(sorted)-1])
+	fmt.Printf("  p50 %.2f ms  p95 %.2f ms  p99 %.2f ms\n", percentile(0.50), percentile(0.95), percentile(0.99))
+}
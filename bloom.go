@@ -0,0 +1,189 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"m-box_explore/render"
+)
+
+// bloomTextureUnit and the unit after it hold the bloom passes' inputs.
+const bloomTextureUnit = 4
+
+// Bloom settings. Pixels brighter than bloomThreshold (luminance, 0-1) glow,
+// and bloomIntensity scales the glow added back onto the image.
+var (
+	bloomEnabled   bool
+	bloomThreshold float32 = 0.6
+	bloomIntensity float32 = 0.8
+)
+
+var (
+	bloomExtractShaderSource = `
+		#version 330 core
+		in vec2 uv;
+		out vec4 FragColor;
+		uniform sampler2D image;
+		uniform float bloomThreshold;
+		void main() {
+			vec3 color = texture(image, uv).rgb;
+			float luma = dot(color, vec3(0.2126, 0.7152, 0.0722));
+			// Keep only the part of the brightness above the threshold
+			FragColor = vec4(color * max(luma - bloomThreshold, 0.0) / max(luma, 1e-4), 1.0);
+		}
+	` + "\x00"
+
+	bloomBlurShaderSource = `
+		#version 330 core
+		in vec2 uv;
+		out vec4 FragColor;
+		uniform sampler2D image;
+		uniform vec2 direction; // one texel along the blur axis
+		const float weights[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+		void main() {
+			vec3 color = texture(image, uv).rgb * weights[0];
+			for (int i = 1; i < 5; i++) {
+				color += texture(image, uv + direction * float(i)).rgb * weights[i];
+				color += texture(image, uv - direction * float(i)).rgb * weights[i];
+			}
+			FragColor = vec4(color, 1.0);
+		}
+	` + "\x00"
+
+	bloomCompositeShaderSource = `
+		#version 330 core
+		in vec2 uv;
+		out vec4 FragColor;
+		uniform sampler2D image;
+		uniform sampler2D glow;
+		uniform float bloomIntensity;
+		void main() {
+			vec4 color = texture(image, uv);
+			FragColor = vec4(color.rgb + texture(glow, uv).rgb * bloomIntensity, color.a);
+		}
+	` + "\x00"
+)
+
+// bloom renders the scene off-screen, pulls out its bright parts at half
+// resolution, blurs them with a separable Gaussian and adds them back.
+type bloom struct {
+	extract   *render.Program
+	blur      *render.Program
+	composite *render.Program
+	quad      *render.Quad
+
+	sceneFBO     uint32
+	sceneTexture uint32
+	// Half-size targets the blur ping-pongs between
+	glowFBOs     [2]uint32
+	glowTextures [2]uint32
+	width        int32
+	height       int32
+}
+
+func newBloom(quad *render.Quad) (*bloom, error) {
+	extract, err := render.NewProgram(render.QuadVertexShader, bloomExtractShaderSource)
+	if err != nil {
+		return nil, err
+	}
+	blur, err := render.NewProgram(render.QuadVertexShader, bloomBlurShaderSource)
+	if err != nil {
+		return nil, err
+	}
+	composite, err := render.NewProgram(render.QuadVertexShader, bloomCompositeShaderSource)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bloom{extract: extract, blur: blur, composite: composite, quad: quad}
+	b.sceneFBO, b.sceneTexture = newColorTarget()
+	for i := range b.glowFBOs {
+		b.glowFBOs[i], b.glowTextures[i] = newColorTarget()
+	}
+	return b, nil
+}
+
+// newColorTarget creates a framebuffer with a single linearly filtered color
+// texture. Storage is allocated by resize.
+func newColorTarget() (fbo uint32, texture uint32) {
+	gl.GenFramebuffers(1, &fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, texture, 0)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	return fbo, texture
+}
+
+func (b *bloom) resize(w, h int32) {
+	if w == b.width && h == b.height {
+		return
+	}
+	b.width, b.height = w, h
+
+	gl.BindTexture(gl.TEXTURE_2D, b.sceneTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, w, h, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	for _, texture := range b.glowTextures {
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, max(w/2, 1), max(h/2, 1), 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	}
+}
+
+// render runs drawScene into the bloom's scene target and composites the
+// result, glow included, onto the framebuffer that was bound beforehand.
+func (b *bloom) render(drawScene func()) {
+	b.resize(fbWidth, fbHeight)
+	halfW, halfH := max(fbWidth/2, 1), max(fbHeight/2, 1)
+
+	var target int32
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &target)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, b.sceneFBO)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	drawScene()
+
+	// Bright pass into the first glow target
+	gl.BindFramebuffer(gl.FRAMEBUFFER, b.glowFBOs[0])
+	gl.Viewport(0, 0, halfW, halfH)
+	b.extract.Use()
+	b.bindInput(b.extract, b.sceneTexture)
+	gl.Uniform1f(b.extract.Uniform("bloomThreshold"), bloomThreshold)
+	b.quad.Draw()
+
+	// Horizontal then vertical blur, ending back in the first target
+	b.blur.Use()
+	directionUniform := b.blur.Uniform("direction")
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, b.glowFBOs[1])
+	b.bindInput(b.blur, b.glowTextures[0])
+	gl.Uniform2f(directionUniform, 1/float32(halfW), 0)
+	b.quad.Draw()
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, b.glowFBOs[0])
+	b.bindInput(b.blur, b.glowTextures[1])
+	gl.Uniform2f(directionUniform, 0, 1/float32(halfH))
+	b.quad.Draw()
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(target))
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+	b.composite.Use()
+	b.bindInput(b.composite, b.sceneTexture)
+	gl.ActiveTexture(gl.TEXTURE0 + bloomTextureUnit + 1)
+	gl.BindTexture(gl.TEXTURE_2D, b.glowTextures[0])
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.Uniform1i(b.composite.Uniform("glow"), bloomTextureUnit+1)
+	gl.Uniform1f(b.composite.Uniform("bloomIntensity"), bloomIntensity)
+	b.quad.Draw()
+}
+
+// bindInput binds texture as program's "image" sampler.
+func (b *bloom) bindInput(program *render.Program, texture uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + bloomTextureUnit)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.Uniform1i(program.Uniform("image"), bloomTextureUnit)
+}
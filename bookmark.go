@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"log"
+	"m-box_explore/camera"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const bookmarksFile = "bookmarks.json"
+
+// Bookmark is a saved viewpoint together with the fractal parameters that
+// produced it.
+type Bookmark struct {
+	Position      mgl32.Vec3 `json:"position"`
+	Yaw           float32    `json:"yaw"`
+	Pitch         float32    `json:"pitch"`
+	Scale         float32    `json:"scale"`
+	MaxIterations int32      `json:"maxIterations"`
+}
+
+// saveBookmark appends b to the bookmark list stored at path.
+func saveBookmark(path string, b Bookmark) error {
+	bookmarks, err := loadBookmarks(path)
+	if err != nil {
+		return err
+	}
+	bookmarks = append(bookmarks, b)
+
+	data, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadBookmarks reads the bookmark list at path. A missing file is treated as
+// an empty list.
+func loadBookmarks(path string) ([]Bookmark, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []Bookmark
+	if err := json.Unmarshal(data, &bookmarks); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return bookmarks, nil
+}
+
+func addBookmark(cam *camera.Camera) {
+	b := Bookmark{
+		Position:      cam.Position,
+		Yaw:           cam.Yaw,
+		Pitch:         cam.Pitch,
+		Scale:         scale,
+		MaxIterations: maxIterations,
+	}
+	if err := saveBookmark(bookmarksFile, b); err != nil {
+		log.Println("failed to save bookmark:", err)
+		return
+	}
+	fmt.Println("saved bookmark to", bookmarksFile)
+}
+
+func jumpToBookmark(cam *camera.Camera, index int) {
+	bookmarks, err := loadBookmarks(bookmarksFile)
+	if err != nil {
+		log.Println("failed to load bookmarks:", err)
+		return
+	}
+	if index >= len(bookmarks) {
+		fmt.Printf("no bookmark %d\n", index+1)
+		return
+	}
+
+	b := bookmarks[index]
+	cam.Position = b.Position
+	cam.Velocity = mgl32.Vec3{}
+	cam.Yaw = b.Yaw
+	cam.Pitch = b.Pitch
+	cam.UpdateFront()
+	scale = mgl32.Clamp(b.Scale, minScale, maxScale)
+	maxIterations = b.MaxIterations
+}
+
+// printViewCommand prints a command line that reopens the explorer at the
+// current view, for sharing a viewpoint without a bookmarks file.
+func printViewCommand(cam *camera.Camera) {
+	f := func(x float32) string {
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	}
+	fmt.Printf("%s -pos %s,%s,%s -yaw %s -pitch %s -scale %s -iterations %d -fractal %d -foldingLimit %s\n",
+		filepath.Base(os.Args[0]),
+		f(cam.Position[0]), f(cam.Position[1]), f(cam.Position[2]),
+		f(cam.Yaw), f(cam.Pitch), f(scale), maxIterations, fractalType, f(foldingLimit))
+}
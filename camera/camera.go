@@ -0,0 +1,214 @@
+// Package camera implements the free-fly camera used to explore the fractal.
+package camera
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// Direction is a movement direction relative to the camera's orientation.
+type Direction int
+
+const (
+	Forward Direction = iota
+	Backward
+	Left
+	Right
+	Ascend  // straight up, regardless of where the camera looks
+	Descend // straight down
+)
+
+const (
+	MoveSpeed = 3.0  // top speed in world units per second
+	rollSpeed = 45.0 // degrees per second
+
+	// Velocity decays by a factor of e every 1/damping seconds. Holding a key
+	// accelerates towards a terminal speed of acceleration/damping.
+	damping      = 4.0
+	acceleration = MoveSpeed * damping
+)
+
+var worldUp = mgl32.Vec3{0, 1, 0}
+
+// Camera is a free-fly camera driven by yaw/pitch mouse look. With Walk set,
+// forward and sideways movement stay in the horizontal plane.
+type Camera struct {
+	Position    mgl32.Vec3
+	Velocity    mgl32.Vec3
+	Front       mgl32.Vec3
+	Up          mgl32.Vec3
+	Yaw         float32
+	Pitch       float32
+	Roll        float32
+	Sensitivity float32
+	Walk        bool
+
+	// SpeedFactor scales MoveSpeed, for sprinting and crawling
+	SpeedFactor float32
+
+	// MouseSmoothing in [0,1) spreads mouse look over several frames; 0
+	// applies it immediately
+	MouseSmoothing float32
+	pendingYaw     float32
+	pendingPitch   float32
+}
+
+// New returns a camera at position looking down -Z.
+func New(position mgl32.Vec3) *Camera {
+	c := &Camera{
+		Position:    position,
+		Yaw:         -90.0,
+		Sensitivity: 0.05,
+		SpeedFactor: 1,
+	}
+	c.UpdateFront()
+	return c
+}
+
+// ProcessKeyboard accelerates the camera in the given direction for dt
+// seconds. The position itself changes in Update.
+func (c *Camera) ProcessKeyboard(dir Direction, dt float32) {
+	forward := c.Front
+	right := c.Front.Cross(c.Up).Normalize()
+	if c.Walk {
+		// Pitch never reaches ±90, so the flattened front is never zero
+		forward = mgl32.Vec3{c.Front[0], 0, c.Front[2]}.Normalize()
+		right = forward.Cross(worldUp)
+	}
+
+	dv := acceleration * c.SpeedFactor * dt
+	switch dir {
+	case Forward:
+		c.Velocity = c.Velocity.Add(forward.Mul(dv))
+	case Backward:
+		c.Velocity = c.Velocity.Sub(forward.Mul(dv))
+	case Left:
+		c.Velocity = c.Velocity.Sub(right.Mul(dv))
+	case Right:
+		c.Velocity = c.Velocity.Add(right.Mul(dv))
+	case Ascend:
+		c.Velocity = c.Velocity.Add(worldUp.Mul(dv))
+	case Descend:
+		c.Velocity = c.Velocity.Sub(worldUp.Mul(dv))
+	}
+}
+
+// Update integrates the camera's velocity over dt seconds and applies
+// damping so it glides to a stop once input stops. With mouse smoothing on,
+// it also turns by part of the mouse look still pending.
+func (c *Camera) Update(dt float32) {
+	c.Position = c.Position.Add(c.Velocity.Mul(dt))
+	c.Velocity = c.Velocity.Mul(float32(math.Exp(-damping * float64(dt))))
+
+	if c.pendingYaw != 0 || c.pendingPitch != 0 {
+		// Take 1-MouseSmoothing of what's left per 60Hz frame, whatever the
+		// actual frame rate
+		f := 1 - float32(math.Pow(float64(c.MouseSmoothing), float64(dt)*60))
+		dyaw, dpitch := c.pendingYaw*f, c.pendingPitch*f
+		c.pendingYaw -= dyaw
+		c.pendingPitch -= dpitch
+		c.Rotate(dyaw, dpitch)
+	}
+}
+
+// Idle reports whether the camera has come to rest, with its velocity
+// decayed away and no smoothed mouse look left to apply.
+func (c *Camera) Idle() bool {
+	const epsilon = 1e-4
+	return c.Velocity.Len() < epsilon &&
+		mgl32.Abs(c.pendingYaw) < epsilon && mgl32.Abs(c.pendingPitch) < epsilon
+}
+
+// ProcessMouse applies a cursor offset (in pixels) to yaw and pitch, or
+// queues it for Update when mouse smoothing is on.
+func (c *Camera) ProcessMouse(dx, dy float64) {
+	dyaw := float32(dx * float64(c.Sensitivity))
+	dpitch := float32(dy * float64(c.Sensitivity))
+	if c.MouseSmoothing <= 0 {
+		c.Rotate(dyaw, dpitch)
+		return
+	}
+	c.pendingYaw += dyaw
+	c.pendingPitch += dpitch
+}
+
+// Rotate turns the camera by the given yaw and pitch deltas in degrees,
+// keeping pitch short of straight up or down and yaw within [-180, 180).
+func (c *Camera) Rotate(dyaw, dpitch float32) {
+	c.Yaw = wrapYaw(c.Yaw + dyaw)
+	c.Pitch += dpitch
+
+	if c.Pitch > 89.0 {
+		c.Pitch = 89.0
+	}
+	if c.Pitch < -89.0 {
+		c.Pitch = -89.0
+	}
+
+	c.UpdateFront()
+}
+
+// LookAt turns the camera to face target, keeping its roll and dropping any
+// smoothed mouse look still pending.
+func (c *Camera) LookAt(target mgl32.Vec3) {
+	c.Face(target.Sub(c.Position))
+}
+
+// Face points the camera along dir, recomputing Yaw and Pitch from it with
+// yaw wrapped into [-180, 180). It keeps the roll and drops any smoothed
+// mouse look still pending, so Face(Front) settles the angles without
+// turning the camera.
+func (c *Camera) Face(dir mgl32.Vec3) {
+	if dir.Len() == 0 {
+		return
+	}
+	dir = dir.Normalize()
+	c.Yaw = wrapYaw(mgl32.RadToDeg(float32(math.Atan2(float64(dir[2]), float64(dir[0])))))
+	c.Pitch = mgl32.Clamp(mgl32.RadToDeg(float32(math.Asin(float64(dir[1])))), -89, 89)
+	c.pendingYaw, c.pendingPitch = 0, 0
+	c.UpdateFront()
+}
+
+// wrapYaw brings an angle in degrees into [-180, 180), so yaw doesn't grow
+// without bound as the camera keeps turning.
+func wrapYaw(yaw float32) float32 {
+	yaw = float32(math.Mod(float64(yaw)+180, 360))
+	if yaw < 0 {
+		yaw += 360
+	}
+	return yaw - 180
+}
+
+// ProcessRoll rotates the camera around its forward axis for dt seconds in the
+// direction given by the sign of dir.
+func (c *Camera) ProcessRoll(dir float32, dt float32) {
+	c.Roll += dir * rollSpeed * dt
+	c.UpdateFront()
+}
+
+// Orbit places the camera radius units from target along its current view
+// direction, so it keeps looking at target as yaw and pitch change.
+func (c *Camera) Orbit(target mgl32.Vec3, radius float32) {
+	c.Position = target.Sub(c.Front.Mul(radius))
+}
+
+// ViewMatrix is the world-to-view transform for the camera's position and
+// orientation.
+func (c *Camera) ViewMatrix() mgl32.Mat4 {
+	return mgl32.LookAtV(c.Position, c.Position.Add(c.Front), c.Up)
+}
+
+// UpdateFront recomputes Front and Up from Yaw, Pitch and Roll. Call it after
+// setting those fields directly.
+func (c *Camera) UpdateFront() {
+	front := mgl32.Vec3{
+		float32(math.Cos(float64(mgl32.DegToRad(c.Yaw))) * math.Cos(float64(mgl32.DegToRad(c.Pitch)))),
+		float32(math.Sin(float64(mgl32.DegToRad(c.Pitch)))),
+		float32(math.Sin(float64(mgl32.DegToRad(c.Yaw))) * math.Cos(float64(mgl32.DegToRad(c.Pitch)))),
+	}
+	c.Front = front.Normalize()
+
+	right := c.Front.Cross(worldUp).Normalize()
+	up := right.Cross(c.Front)
+	c.Up = mgl32.QuatRotate(mgl32.DegToRad(c.Roll), c.Front).Rotate(up).Normalize()
+}
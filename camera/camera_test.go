@@ -0,0 +1,228 @@
+package camera
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+const epsilon = 1e-4
+
+// approxVec3 compares component by component; mgl32's own comparisons are
+// relative, which is far too strict next to zero.
+func approxVec3(a, b mgl32.Vec3) bool {
+	for i := range a {
+		if mgl32.Abs(a[i]-b[i]) > epsilon {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRotateClampsPitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		pitch  float32
+		dpitch float32
+		want   float32
+	}{
+		{"within range", 0, 45, 45},
+		{"past straight up", 0, 100, 89},
+		{"past straight down", 0, -100, -89},
+		{"from the limit", 89, 10, 89},
+		{"back from the limit", 89, -10, 79},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(mgl32.Vec3{})
+			c.Pitch = tt.pitch
+			c.Rotate(0, tt.dpitch)
+			if mgl32.Abs(c.Pitch-tt.want) > epsilon {
+				t.Errorf("Pitch = %v, want %v", c.Pitch, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMouseClampsPitch(t *testing.T) {
+	tests := []struct {
+		name string
+		dy   float64
+		want float32
+	}{
+		{"small move", 100, 5},
+		{"far up", 10000, 89},
+		{"far down", -10000, -89},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(mgl32.Vec3{})
+			c.ProcessMouse(0, tt.dy)
+			if mgl32.Abs(c.Pitch-tt.want) > epsilon {
+				t.Errorf("Pitch = %v, want %v", c.Pitch, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotateWrapsYaw(t *testing.T) {
+	tests := []struct {
+		name string
+		yaw  float32
+		dyaw float32
+		want float32
+	}{
+		{"no turn", -90, 0, -90},
+		{"past 180", 170, 20, -170},
+		{"past -180", -170, -20, 170},
+		{"onto 180", 179, 1, -180},
+		{"full turns", 30, 720, 30},
+		{"full turns back", 30, -1080, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(mgl32.Vec3{})
+			c.Yaw = tt.yaw
+			c.Rotate(tt.dyaw, 0)
+			if mgl32.Abs(c.Yaw-tt.want) > epsilon {
+				t.Errorf("Yaw = %v, want %v", c.Yaw, tt.want)
+			}
+			if c.Yaw < -180 || c.Yaw >= 180 {
+				t.Errorf("Yaw = %v, outside [-180, 180)", c.Yaw)
+			}
+			// Wrapping must leave the camera facing the same way as the
+			// unwrapped angle would
+			unwrapped := New(mgl32.Vec3{})
+			unwrapped.Yaw = tt.yaw + tt.dyaw
+			unwrapped.UpdateFront()
+			if !approxVec3(c.Front, unwrapped.Front) {
+				t.Errorf("Front = %v, want %v", c.Front, unwrapped.Front)
+			}
+		})
+	}
+}
+
+func TestUpdateFront(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaw, pitch float32
+		wantFront  mgl32.Vec3
+	}{
+		{"default", -90, 0, mgl32.Vec3{0, 0, -1}},
+		{"yaw 0", 0, 0, mgl32.Vec3{1, 0, 0}},
+		{"yaw 90", 90, 0, mgl32.Vec3{0, 0, 1}},
+		{"yaw 180", 180, 0, mgl32.Vec3{-1, 0, 0}},
+		{"pitched up", -90, 45, mgl32.Vec3{0, 0.70710677, -0.70710677}},
+		{"pitched down", 0, -45, mgl32.Vec3{0.70710677, -0.70710677, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(mgl32.Vec3{1, 2, 3})
+			c.Yaw, c.Pitch = tt.yaw, tt.pitch
+			c.UpdateFront()
+			if !approxVec3(c.Front, tt.wantFront) {
+				t.Errorf("Front = %v, want %v", c.Front, tt.wantFront)
+			}
+			if mgl32.Abs(c.Up.Dot(c.Front)) > epsilon || mgl32.Abs(c.Up.Len()-1) > epsilon {
+				t.Errorf("Up = %v, want a unit vector perpendicular to Front", c.Up)
+			}
+
+			// The view matrix puts the point ahead of the camera on the
+			// -Z axis and Up along +Y
+			view := c.ViewMatrix()
+			ahead := view.Mul4x1(c.Position.Add(c.Front).Vec4(1)).Vec3()
+			if !approxVec3(ahead, mgl32.Vec3{0, 0, -1}) {
+				t.Errorf("view of the point ahead = %v, want (0, 0, -1)", ahead)
+			}
+			above := view.Mul4x1(c.Position.Add(c.Up).Vec4(1)).Vec3()
+			if !approxVec3(above, mgl32.Vec3{0, 1, 0}) {
+				t.Errorf("view of the point above = %v, want (0, 1, 0)", above)
+			}
+		})
+	}
+}
+
+func TestProcessKeyboardStrafe(t *testing.T) {
+	tests := []struct {
+		name             string
+		yaw, pitch, roll float32
+	}{
+		{"default", -90, 0, 0},
+		{"turned", 30, 0, 0},
+		{"pitched", -90, 60, 0},
+		{"rolled", 45, -30, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dir := range []Direction{Left, Right} {
+				c := New(mgl32.Vec3{})
+				c.Yaw, c.Pitch, c.Roll = tt.yaw, tt.pitch, tt.roll
+				c.UpdateFront()
+				c.ProcessKeyboard(dir, 0.1)
+
+				v := c.Velocity
+				if v.Len() < epsilon {
+					t.Fatalf("direction %d: no movement", dir)
+				}
+				if d := v.Normalize().Dot(c.Front); mgl32.Abs(d) > epsilon {
+					t.Errorf("direction %d: velocity %v has %v along Front", dir, v, d)
+				}
+				if d := v.Normalize().Dot(c.Up); mgl32.Abs(d) > epsilon {
+					t.Errorf("direction %d: velocity %v has %v along Up", dir, v, d)
+				}
+
+				// Right moves along Front x Up and Left against it
+				want := float32(1)
+				if dir == Left {
+					want = -1
+				}
+				if got := v.Normalize().Dot(c.Front.Cross(c.Up).Normalize()); mgl32.Abs(got-want) > epsilon {
+					t.Errorf("direction %d: velocity %v points %v along Front x Up, want %v", dir, v, got, want)
+				}
+			}
+		})
+	}
+
+	c := New(mgl32.Vec3{})
+	c.ProcessKeyboard(Right, 0.1)
+	if c.Velocity[0] <= 0 {
+		t.Errorf("Right from the default view moved along %v, want +X", c.Velocity)
+	}
+}
+
+func TestOrbitThenFace(t *testing.T) {
+	tests := []struct {
+		name             string
+		yaw, pitch, roll float32
+	}{
+		{"default", -90, 0, 0},
+		{"turned and pitched", 135, 30, 0},
+		{"steep", -45, -80, 0},
+		{"at the pitch limit", 60, 89, 0},
+		{"rolled", 170, 20, 45},
+	}
+	target := mgl32.Vec3{1, -2, 0.5}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(mgl32.Vec3{})
+			c.Yaw, c.Pitch, c.Roll = tt.yaw, tt.pitch, tt.roll
+			c.UpdateFront()
+			front := c.Front
+
+			c.Orbit(target, 3)
+			if d := c.Position.Sub(target).Len(); mgl32.Abs(d-3) > epsilon {
+				t.Errorf("distance from target = %v, want 3", d)
+			}
+			// Facing the way the camera already looks must not turn it
+			c.Face(c.Front)
+			if !approxVec3(c.Front, front) {
+				t.Errorf("Front = %v, want %v", c.Front, front)
+			}
+			if c.Pitch < -89 || c.Pitch > 89 {
+				t.Errorf("Pitch = %v, outside [-89, 89]", c.Pitch)
+			}
+			if ahead := c.Position.Add(c.Front.Mul(3)); !approxVec3(ahead, target) {
+				t.Errorf("point 3 ahead = %v, want the target %v", ahead, target)
+			}
+		})
+	}
+}
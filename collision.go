@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/go-gl/mathgl/mgl64"
+	"m-box_explore/camera"
+)
+
+// Collision settings. With collision on, the camera is kept at least
+// collisionRadius (world units) from the fractal surface.
+var (
+	collisionEnabled bool
+	collisionRadius  float64 = 0.02
+)
+
+// resolveCollision checks the camera's move from `from` to its current
+// position against the distance estimator. A move that would bring it
+// closer than collisionRadius loses its component into the surface, so the
+// camera slides along it; if that still isn't clear the move is undone.
+// The eye the view is drawn from is debugOffset away from the camera, so
+// that is what is kept clear.
+func resolveCollision(cam *camera.Camera, from mgl32.Vec3) {
+	offset := vec64(debugOffset)
+	start := vec64(from).Add(offset)
+	end := vec64(cam.Position).Add(offset)
+	if end == start {
+		return
+	}
+
+	// Every candidate is estimated as seen from end, where the next frame
+	// would be drawn from, so they share one level of detail
+	d := sceneDistance(end, end)
+	// Moving away from the surface is always allowed, so a camera that
+	// starts inside the radius can still back out
+	if !(d < collisionRadius) || d >= sceneDistance(start, end) {
+		return
+	}
+
+	n := sceneNormal(end, end)
+	move := end.Sub(start)
+	if into := move.Dot(n); into < 0 {
+		move = move.Sub(n.Mul(into))
+	}
+	slid := start.Add(move)
+	if sceneDistance(slid, end) < collisionRadius {
+		slid = start
+	}
+	cam.Position = vec32(slid.Sub(offset))
+
+	// Drop the velocity heading into the surface so it doesn't keep pushing
+	v := vec64(cam.Velocity)
+	if into := v.Dot(n); into < 0 {
+		cam.Velocity = vec32(v.Sub(n.Mul(into)))
+	}
+}
+
+func vec64(v mgl32.Vec3) mgl64.Vec3 {
+	return mgl64.Vec3{float64(v[0]), float64(v[1]), float64(v[2])}
+}
+
+func vec32(v mgl64.Vec3) mgl32.Vec3 {
+	return mgl32.Vec3{float32(v[0]), float32(v[1]), float32(v[2])}
+}
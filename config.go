@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Config is the startup configuration main builds the window, camera and
+// fractal parameters from. Flags and renderOnce's callers start from
+// DefaultConfig and change what they need.
+type Config struct {
+	Width  int // window size, or the frame size for renderOnce
+	Height int
+
+	Position    mgl32.Vec3
+	Yaw         float32 // degrees
+	Pitch       float32
+	Sensitivity float32 // mouse look degrees per pixel
+	FOV         float32 // vertical, in degrees
+
+	Scale         float32
+	MaxIterations int32
+	FractalType   int32
+	FoldingLimit  float32
+}
+
+// DefaultConfig returns the explorer's defaults: the Mandelbox at scale 2
+// from the origin, looking down -Z.
+func DefaultConfig() Config {
+	return Config{
+		Width:         1280,
+		Height:        720,
+		Yaw:           -90,
+		Sensitivity:   0.05,
+		FOV:           90,
+		Scale:         2,
+		MaxIterations: 100,
+		FoldingLimit:  1,
+	}
+}
+
+// apply makes cfg the current settings.
+func (cfg Config) apply() {
+	width, height = cfg.Width, cfg.Height
+	startPos = cfg.Position
+	startYaw = cfg.Yaw
+	startPitch = mgl32.Clamp(cfg.Pitch, -89, 89)
+	mouseSensitivity = cfg.Sensitivity
+	fov = mgl32.Clamp(cfg.FOV, 20, 120)
+
+	scale = mgl32.Clamp(cfg.Scale, minScale, maxScale)
+	maxIterations = cfg.MaxIterations
+	fractalType = cfg.FractalType
+	foldingLimit = cfg.FoldingLimit
+}
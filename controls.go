@@ -0,0 +1,744 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"m-box_explore/input"
+	"math"
+)
+
+// Holding the sprint key multiplies the movement speed by sprintFactor, and
+// holding the crawl key divides it by crawlFactor.
+var (
+	sprintFactor float32 = 5.0
+	crawlFactor  float32 = 10.0
+)
+
+// processInput applies held-key movement, scaled by deltaTime so travel speed
+// doesn't depend on frame rate or key repeat rate.
+func processInput(in *input.Handler, cam *camera.Camera) {
+	cam.SpeedFactor = 1
+	if in.Held("sprint") {
+		cam.SpeedFactor *= sprintFactor
+	}
+	if in.Held("crawl") {
+		cam.SpeedFactor /= crawlFactor
+	}
+
+	if juliaMouse {
+		x, y := in.CursorPos()
+		juliaC[0] = float32(x*2-1) * juliaMouseRange
+		juliaC[1] = float32(y*2-1) * juliaMouseRange
+	}
+
+	if orbitMode {
+		processOrbitInput(in, cam)
+	} else {
+		processFlyInput(in, cam)
+	}
+
+	if in.Held("rollLeft") {
+		cam.ProcessRoll(-1, deltaTime)
+	}
+	if in.Held("rollRight") {
+		cam.ProcessRoll(1, deltaTime)
+	}
+	if in.Held("scaleUp") {
+		scale += scaleSpeed * deltaTime
+	}
+	if in.Held("scaleDown") {
+		scale -= scaleSpeed * deltaTime
+	}
+	scale = mgl32.Clamp(scale, minScale, maxScale)
+}
+
+func processFlyInput(in *input.Handler, cam *camera.Camera) {
+	if in.Held("forward") {
+		cam.ProcessKeyboard(camera.Forward, deltaTime)
+	}
+	if in.Held("back") {
+		cam.ProcessKeyboard(camera.Backward, deltaTime)
+	}
+	if in.Held("strafeLeft") {
+		cam.ProcessKeyboard(camera.Left, deltaTime)
+	}
+	if in.Held("strafeRight") {
+		cam.ProcessKeyboard(camera.Right, deltaTime)
+	}
+	if in.Held("ascend") {
+		cam.ProcessKeyboard(camera.Ascend, deltaTime)
+	}
+	if in.Held("descend") {
+		cam.ProcessKeyboard(camera.Descend, deltaTime)
+	}
+}
+
+// processOrbitInput moves in and out along the orbit radius with W/S and
+// raises or lowers the orbit target with D/A.
+func processOrbitInput(in *input.Handler, cam *camera.Camera) {
+	step := float32(camera.MoveSpeed) * cam.SpeedFactor * deltaTime
+	if in.Held("forward") {
+		orbitRadius -= step
+	}
+	if in.Held("back") {
+		orbitRadius += step
+	}
+	if in.Held("strafeLeft") {
+		orbitTarget[1] -= step
+	}
+	if in.Held("strafeRight") {
+		orbitTarget[1] += step
+	}
+	if orbitRadius < 0.1 {
+		orbitRadius = 0.1
+	}
+	cam.Orbit(orbitTarget, orbitRadius)
+}
+
+// toggleOrbit switches between free flight and orbiting without moving or
+// turning the camera. The orbit target is moved onto the view direction:
+// onto the surface under the crosshair if there is one in range, otherwise
+// as far ahead as the old target was.
+func toggleOrbit(cam *camera.Camera) {
+	orbitMode = !orbitMode
+	cam.Velocity = mgl32.Vec3{}
+	cam.Face(cam.Front)
+	if !orbitMode {
+		return
+	}
+
+	orbitRadius = cam.Position.Sub(orbitTarget).Len()
+	// The view is drawn from the offset eye, so that is where the surface
+	// under the crosshair is found from
+	if t, ok := marchDistance(vec64(cam.Position.Add(debugOffset)), vec64(cam.Front)); ok {
+		orbitRadius = float32(t)
+	}
+	orbitRadius = max(orbitRadius, 0.1)
+	orbitTarget = cam.Position.Add(cam.Front.Mul(orbitRadius))
+	cam.Orbit(orbitTarget, orbitRadius)
+}
+
+func mouseMove(cam *camera.Camera, dx, dy float64) {
+	cam.ProcessMouse(dx, dy)
+	if orbitMode {
+		cam.Orbit(orbitTarget, orbitRadius)
+	}
+}
+
+// zoomView multiplies debugZoom by factor, magnifying the middle of the
+// view without moving the camera or changing the field of view.
+func zoomView(factor float32) {
+	debugZoom = mgl32.Clamp(debugZoom*factor, 0.1, 1000)
+	fmt.Printf("zoom: %.2fx\n", debugZoom)
+}
+
+// debugAxis is the axis of debugOffset that Up and Down move along in place
+// of adjusting bloom, chosen with the debugAxis key, or -1 for none.
+var debugAxis = -1
+
+var axisNames = [3]string{"x", "y", "z"}
+
+// nudgeDebugOffset moves debugOffset by delta along axis and reports where
+// it is now.
+func nudgeDebugOffset(axis int, delta float32) {
+	debugOffset[axis] += delta
+	fmt.Printf("debug offset: %.2f, %.2f, %.2f\n", debugOffset[0], debugOffset[1], debugOffset[2])
+}
+
+// panDebugOffset moves debugOffset in the camera's right/up plane by the
+// mouse offsets, by less the further debugZoom is zoomed in.
+func panDebugOffset(cam *camera.Camera, dx, dy float64) {
+	const unitsPerPixel = 0.005
+	right := cam.Front.Cross(cam.Up).Normalize()
+	k := unitsPerPixel / debugZoom
+	debugOffset = debugOffset.Add(right.Mul(float32(dx) * k)).Add(cam.Up.Mul(float32(dy) * k))
+}
+
+// With juliaMouse set, the cursor's position across the window drives juliaC's
+// x and y over ±juliaMouseRange, and the scroll wheel its z.
+var juliaMouse bool
+
+const juliaMouseRange = 2.0
+
+// nudgeJuliaC steps component i of juliaC in the direction given by the sign
+// of dir. By default the number pad drives it: 4/6 for x, 2/8 for y and 1/9
+// for z.
+func nudgeJuliaC(i int, dir float32) {
+	const step = 0.05
+	juliaC[i] += dir * step
+	fmt.Printf("juliaC: %.2f, %.2f, %.2f\n", juliaC[0], juliaC[1], juliaC[2])
+}
+
+// rackFocus moves the focus plane nearer or farther; with Shift it stops the
+// aperture down or opens it up instead.
+func rackFocus(farther bool, mods glfw.ModifierKey) {
+	if mods&glfw.ModShift != 0 {
+		if farther {
+			aperture *= 1.25
+		} else {
+			aperture /= 1.25
+		}
+		fmt.Printf("aperture: %.2f\n", aperture)
+		return
+	}
+
+	if farther {
+		focusDistance *= 1.1
+	} else {
+		focusDistance /= 1.1
+	}
+	fmt.Printf("focus distance: %.3f\n", focusDistance)
+}
+
+// adjustMouseSmoothing keeps smoothing short of 1, where the view would
+// never catch up with the mouse.
+func adjustMouseSmoothing(cam *camera.Camera, delta float32) {
+	cam.MouseSmoothing = mgl32.Clamp(cam.MouseSmoothing+delta, 0, 0.9)
+	fmt.Printf("mouse smoothing: %.1f\n", cam.MouseSmoothing)
+}
+
+// setMaxDistance moves the ray-march cutoff and the far plane with it.
+func setMaxDistance(d float32) {
+	maxDistance = mgl32.Clamp(d, max(1, nearPlane*2), 10000)
+	updateProjection()
+	fmt.Printf("maxDistance: %.0f\n", maxDistance)
+}
+
+// resetParameters puts the fractal parameters and debug view back to their
+// defaults, whatever the flags or a resumed session set, and returns the
+// camera to its starting view, for recovering from settings that leave
+// nothing visible.
+func resetParameters(cam *camera.Camera) {
+	defaults := DefaultConfig()
+	scale = defaults.Scale
+	baseScale = scale
+	maxIterations = defaults.MaxIterations
+	foldingLimit = defaults.FoldingLimit
+	debugZoom = 1
+	debugOffset = mgl32.Vec3{}
+	debugAxis = -1
+
+	*cam = *initCamera()
+	if orbitMode {
+		orbitRadius = cam.Position.Sub(orbitTarget).Len()
+	}
+	fmt.Println("reset parameters")
+}
+
+// adjustEscapeRadius moves the Mandelbox bailout radius by delta. Below 1 the
+// distance estimate turns negative, so it stops a little above that.
+func adjustEscapeRadius(delta float32) {
+	escapeRadius = mgl32.Clamp(escapeRadius+delta, 1.5, 100)
+	fmt.Printf("escape radius: %.1f\n", escapeRadius)
+}
+
+// rotateHue shifts the palette by delta, wrapping into [0, 1).
+func rotateHue(delta float32) {
+	hueShift = float32(math.Mod(float64(hueShift+delta)+1, 1))
+	fmt.Printf("hue shift: %.2f\n", hueShift)
+}
+
+// adjustColorGamma multiplies the color curve's gamma by factor, within a
+// range that keeps the palette from collapsing to one end.
+func adjustColorGamma(factor float32) {
+	colorGamma = mgl32.Clamp(colorGamma*factor, 0.1, 10)
+	fmt.Printf("color gamma: %.2f\n", colorGamma)
+}
+
+// adjustGamma moves the display gamma by delta, keeping it in a range where
+// the image is still recognizable.
+func adjustGamma(delta float32) {
+	gamma = mgl32.Clamp(gamma+delta, 0.5, 4)
+	fmt.Printf("gamma: %.1f\n", gamma)
+}
+
+// adjustLODFalloff changes how fast iterations drop off with distance, never
+// below 0, which turns the level of detail off.
+func adjustLODFalloff(delta float32) {
+	lodFalloff = max(lodFalloff+delta, 0)
+	fmt.Printf("iteration LOD falloff: %.2f\n", lodFalloff)
+}
+
+// setNearPlane moves the near plane, keeping it in front of the far one.
+func setNearPlane(d float32) {
+	nearPlane = mgl32.Clamp(d, 1e-6, maxDistance/2)
+	updateProjection()
+	fmt.Printf("near plane: %g\n", nearPlane)
+}
+
+// adjustBloom raises or lowers the bloom intensity in the direction of dir,
+// or the brightness threshold when Shift is held.
+func adjustBloom(dir float32, mods glfw.ModifierKey) {
+	if mods&glfw.ModShift != 0 {
+		bloomThreshold = mgl32.Clamp(bloomThreshold+dir*0.05, 0, 1)
+		fmt.Printf("bloom threshold: %.2f\n", bloomThreshold)
+		return
+	}
+	bloomIntensity = max(bloomIntensity+dir*0.1, 0)
+	fmt.Printf("bloom intensity: %.1f\n", bloomIntensity)
+}
+
+// bindKeys attaches every keyboard action to the input handler. Which key
+// triggers each one comes from the handler's bindings.
+func bindKeys(in *input.Handler, window *glfw.Window, cam *camera.Camera, accum *accumulator) {
+	// Most keys change what is drawn, so start the average over on any of
+	// them rather than tracking every parameter
+	in.OnAnyKey(accum.reset)
+
+	in.OnMouseMove(func(dx, dy float64) {
+		mouseMove(cam, dx, dy)
+	})
+	in.OnDrag(glfw.MouseButtonRight, func(dx, dy float64) {
+		panDebugOffset(cam, dx, dy)
+	})
+	in.OnClick(glfw.MouseButtonLeft, func(x, y float64) {
+		pick(cam, x, y)
+		accum.reset()
+	})
+
+	in.OnPress("captureMouse", func(glfw.ModifierKey) {
+		in.SetCaptured(!in.Captured())
+	})
+	// With Shift, the sensitivity keys adjust mouse smoothing instead
+	in.OnPress("sensitivityDown", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustMouseSmoothing(cam, -0.1)
+			return
+		}
+		cam.Sensitivity -= 0.01
+		if cam.Sensitivity < 0.01 {
+			cam.Sensitivity = 0.01
+		}
+	})
+	in.OnPress("sensitivityUp", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustMouseSmoothing(cam, 0.1)
+			return
+		}
+		cam.Sensitivity += 0.01
+		if cam.Sensitivity > 0.5 {
+			cam.Sensitivity = 0.5
+		}
+	})
+	in.OnPress("screenshot", func(glfw.ModifierKey) {
+		screenshotRequested = true
+	})
+	in.OnPress("antiAliasing", func(mods glfw.ModifierKey) {
+		// Shift+F2 switches the overlays' hardware multisampling instead,
+		// and Ctrl+F2 the FXAA pass
+		if mods&glfw.ModShift != 0 {
+			setMultisample(!msaaEnabled)
+			fmt.Printf("MSAA (%d samples): %v\n", msaaSamples, msaaEnabled)
+			return
+		}
+		if mods&glfw.ModControl != 0 {
+			fxaaEnabled = !fxaaEnabled
+			fmt.Println("FXAA:", fxaaEnabled)
+			return
+		}
+		switch aaSamples {
+		case 1:
+			aaSamples = 2
+		case 2:
+			aaSamples = 4
+		default:
+			aaSamples = 1
+		}
+		fmt.Printf("anti-aliasing: %dx%d\n", aaSamples, aaSamples)
+	})
+	in.OnPress("toggleHUD", func(glfw.ModifierKey) {
+		showHUD = !showHUD
+	})
+	in.OnPress("reloadShaders", func(glfw.ModifierKey) {
+		reloadShaders = true
+	})
+	in.OnPress("orbitMode", func(glfw.ModifierKey) {
+		toggleOrbit(cam)
+	})
+	in.OnPress("walkMode", func(glfw.ModifierKey) {
+		cam.Walk = !cam.Walk
+		fmt.Println("walk mode:", cam.Walk)
+	})
+	in.OnPress("collision", func(glfw.ModifierKey) {
+		collisionEnabled = !collisionEnabled
+		fmt.Println("collision:", collisionEnabled)
+	})
+	// Shift+V switches the curve mapping iteration counts to the palette
+	in.OnPress("colorMode", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			colorCurve = (colorCurve + 1) % numColorCurves
+			fmt.Println("color curve:", colorCurve)
+			return
+		}
+		colorMode = (colorMode + 1) % numColorModes
+	})
+	in.OnPress("juliaMode", func(mods glfw.ModifierKey) {
+		// Shift+Y hands juliaC to the mouse, freeing the cursor to do it
+		if mods&glfw.ModShift != 0 {
+			juliaMouse = !juliaMouse
+			if juliaMouse {
+				juliaMode = true
+				in.SetCaptured(false)
+			}
+			fmt.Println("mouse-driven julia constant:", juliaMouse)
+			return
+		}
+		juliaMode = !juliaMode
+	})
+	in.OnPress("fractalType", func(glfw.ModifierKey) {
+		fractalType = (fractalType + 1) % numFractalTypes
+		fmt.Println("fractal:", fractalNames[fractalType])
+	})
+	// Shift turns the fold rotations the other way
+	for axis, action := range []string{"rotX", "rotY", "rotZ"} {
+		in.OnRepeat(action, func(mods glfw.ModifierKey) {
+			rotateFolds(axis, mods&glfw.ModShift != 0)
+		})
+	}
+	in.OnPress("animateScale", func(glfw.ModifierKey) {
+		animateScale = !animateScale
+		if animateScale {
+			baseScale = scale
+		}
+	})
+	in.OnPress("pauseAnimation", func(glfw.ModifierKey) {
+		animationPaused = !animationPaused
+		fmt.Println("animation paused:", animationPaused)
+	})
+	in.OnRepeat("stepAnimation", func(glfw.ModifierKey) {
+		if animationPaused {
+			animationTime += animationStep
+		}
+	})
+	in.OnPress("fullscreen", func(glfw.ModifierKey) {
+		toggleFullscreen(window)
+	})
+	in.OnPress("recordPath", func(glfw.ModifierKey) {
+		toggleRecording()
+	})
+	in.OnPress("playPath", func(glfw.ModifierKey) {
+		togglePlayback()
+	})
+	in.OnPress("vsync", func(mods glfw.ModifierKey) {
+		// Shift+F8 switches idle power saving instead
+		if mods&glfw.ModShift != 0 {
+			powerSave = !powerSave
+			fmt.Println("power save when idle:", powerSave)
+			return
+		}
+		vsync = !vsync
+		if vsync {
+			glfw.SwapInterval(1)
+		} else {
+			glfw.SwapInterval(0)
+		}
+		fmt.Println("vsync:", vsync)
+	})
+	// Shift+F9 shows how many march steps each pixel took instead
+	in.OnPress("showDepth", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			stepHeatmap = !stepHeatmap
+			fmt.Println("step heatmap:", stepHeatmap)
+			return
+		}
+		showDepth = !showDepth
+	})
+	in.OnPress("adaptiveResolution", func(glfw.ModifierKey) {
+		adaptiveResolution = !adaptiveResolution
+		if !adaptiveResolution {
+			resolutionScale = 1
+		}
+		fmt.Println("adaptive resolution:", adaptiveResolution)
+	})
+	in.OnPress("bloom", func(glfw.ModifierKey) {
+		bloomEnabled = !bloomEnabled
+		fmt.Println("bloom:", bloomEnabled)
+	})
+	in.OnPress("stereo", func(glfw.ModifierKey) {
+		stereo = !stereo
+		anaglyph = false
+		fmt.Println("stereo:", stereo)
+	})
+	in.OnPress("anaglyph", func(mods glfw.ModifierKey) {
+		// Shift swaps which eye gets the red channel
+		if mods&glfw.ModShift != 0 {
+			anaglyphSwap = !anaglyphSwap
+			fmt.Println("anaglyph eyes swapped:", anaglyphSwap)
+			return
+		}
+		anaglyph = !anaglyph
+		stereo = false
+		fmt.Println("anaglyph:", anaglyph)
+	})
+	in.OnPress("crosshair", func(glfw.ModifierKey) {
+		showCrosshair = !showCrosshair
+	})
+	in.OnPress("keyLight", func(glfw.ModifierKey) {
+		keyLightEnabled = !keyLightEnabled
+		fmt.Println("key light:", keyLightEnabled)
+	})
+	in.OnPress("fillLight", func(glfw.ModifierKey) {
+		fillLightEnabled = !fillLightEnabled
+		fmt.Println("fill light:", fillLightEnabled)
+	})
+	in.OnPress("ambientLight", func(glfw.ModifierKey) {
+		ambientEnabled = !ambientEnabled
+		fmt.Println("ambient light:", ambientEnabled)
+	})
+	in.OnPress("minimap", func(glfw.ModifierKey) {
+		showMinimap = !showMinimap
+	})
+	in.OnPress("showBounds", func(glfw.ModifierKey) {
+		showBounds = !showBounds
+	})
+	in.OnPress("depthOfField", func(glfw.ModifierKey) {
+		dofEnabled = !dofEnabled
+		fmt.Println("depth of field:", dofEnabled)
+	})
+	in.OnPress("resetAccumulation", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			accumulate = !accumulate
+			fmt.Println("accumulation:", accumulate)
+		}
+		accum.reset()
+	})
+	// Shift+F5 cycles through the shipped presets instead
+	in.OnPress("addBookmark", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			nextPreset(cam)
+			return
+		}
+		addBookmark(cam)
+	})
+	in.OnPress("printView", func(glfw.ModifierKey) {
+		printViewCommand(cam)
+	})
+	in.OnPress("reset", func(glfw.ModifierKey) {
+		resetParameters(cam)
+	})
+	for i := 0; i < 9; i++ {
+		in.OnPress(fmt.Sprintf("bookmark%d", i+1), func(glfw.ModifierKey) {
+			jumpToBookmark(cam, i)
+		})
+	}
+
+	in.OnRepeat("debugZoomDown", func(glfw.ModifierKey) {
+		zoomView(1 / 1.1)
+	})
+	in.OnRepeat("debugZoomUp", func(glfw.ModifierKey) {
+		zoomView(1.1)
+	})
+	in.OnRepeat("debugUp", func(glfw.ModifierKey) {
+		nudgeDebugOffset(1, 0.1)
+	})
+	in.OnRepeat("debugDown", func(glfw.ModifierKey) {
+		nudgeDebugOffset(1, -0.1)
+	})
+	in.OnRepeat("debugLeft", func(glfw.ModifierKey) {
+		nudgeDebugOffset(0, -0.1)
+	})
+	in.OnRepeat("debugRight", func(glfw.ModifierKey) {
+		nudgeDebugOffset(0, 0.1)
+	})
+	in.OnRepeat("debugNear", func(glfw.ModifierKey) {
+		nudgeDebugOffset(2, -0.1)
+	})
+	in.OnRepeat("debugFar", func(glfw.ModifierKey) {
+		nudgeDebugOffset(2, 0.1)
+	})
+	// Cycles x, y, z and back to none
+	in.OnPress("debugAxis", func(glfw.ModifierKey) {
+		debugAxis++
+		if debugAxis == len(axisNames) {
+			debugAxis = -1
+			fmt.Println("debug offset axis: none (Up/Down adjust bloom)")
+			return
+		}
+		fmt.Printf("debug offset axis: %s (Up/Down to move)\n", axisNames[debugAxis])
+	})
+	in.OnRepeat("foldingLimitDown", func(glfw.ModifierKey) {
+		foldingLimit -= 0.05
+	})
+	in.OnRepeat("foldingLimitUp", func(glfw.ModifierKey) {
+		foldingLimit += 0.05
+	})
+	// With Shift, the epsilon factor keys adjust the iteration level of
+	// detail instead
+	in.OnRepeat("epsilonFactorDown", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustLODFalloff(-0.05)
+			return
+		}
+		epsilonFactor -= 0.1
+		if epsilonFactor < 0 {
+			epsilonFactor = 0
+		}
+		fmt.Printf("epsilonFactor: %.1f\n", epsilonFactor)
+	})
+	in.OnRepeat("epsilonFactorUp", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustLODFalloff(0.05)
+			return
+		}
+		epsilonFactor += 0.1
+		fmt.Printf("epsilonFactor: %.1f\n", epsilonFactor)
+	})
+	in.OnRepeat("fog", func(mods glfw.ModifierKey) {
+		// Shift+F thins the fog, F thickens it
+		if mods&glfw.ModShift != 0 {
+			fogDensity -= 0.01
+			if fogDensity < 0 {
+				fogDensity = 0
+			}
+		} else {
+			fogDensity += 0.01
+		}
+	})
+	in.OnRepeat("focusNearer", func(mods glfw.ModifierKey) {
+		rackFocus(false, mods)
+	})
+	in.OnRepeat("focusFarther", func(mods glfw.ModifierKey) {
+		rackFocus(true, mods)
+	})
+	// While a debug offset axis is active, Up and Down move debugOffset
+	// along it instead
+	in.OnRepeat("bloomUp", func(mods glfw.ModifierKey) {
+		if debugAxis >= 0 {
+			nudgeDebugOffset(debugAxis, 0.1)
+			return
+		}
+		adjustBloom(1, mods)
+	})
+	in.OnRepeat("bloomDown", func(mods glfw.ModifierKey) {
+		if debugAxis >= 0 {
+			nudgeDebugOffset(debugAxis, -0.1)
+			return
+		}
+		adjustBloom(-1, mods)
+	})
+	// With Shift, the color scale keys adjust the color curve's gamma
+	in.OnRepeat("colorScaleUp", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustColorGamma(1.1)
+			return
+		}
+		colorScale = min(colorScale*1.1, 100)
+		fmt.Printf("color scale: %.2f\n", colorScale)
+	})
+	in.OnRepeat("colorScaleDown", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustColorGamma(1 / 1.1)
+			return
+		}
+		colorScale = max(colorScale/1.1, 0.01)
+		fmt.Printf("color scale: %.2f\n", colorScale)
+	})
+	// With Shift, the hue keys adjust the output gamma
+	in.OnRepeat("hueShiftUp", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustGamma(0.1)
+			return
+		}
+		rotateHue(0.02)
+	})
+	in.OnRepeat("hueShiftDown", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustGamma(-0.1)
+			return
+		}
+		rotateHue(-0.02)
+	})
+	// Shift lowers the reflection bounces and shadow steps
+	in.OnRepeat("reflections", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			maxReflections = max(maxReflections-1, 0)
+		} else {
+			maxReflections = min(maxReflections+1, 8)
+		}
+		fmt.Println("reflection bounces:", maxReflections)
+	})
+	in.OnRepeat("shadowSteps", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			softShadowSteps = max(softShadowSteps-8, 0)
+		} else {
+			softShadowSteps = min(softShadowSteps+8, 256)
+		}
+		fmt.Println("shadow steps:", softShadowSteps)
+	})
+	in.OnRepeat("reflectivity", func(mods glfw.ModifierKey) {
+		// Shift+G makes the surface duller, G glossier
+		if mods&glfw.ModShift != 0 {
+			reflectivity -= 0.05
+		} else {
+			reflectivity += 0.05
+		}
+		reflectivity = mgl32.Clamp(reflectivity, 0, 1)
+	})
+	for i, axis := range []string{"X", "Y", "Z"} {
+		in.OnRepeat("julia"+axis+"Down", func(glfw.ModifierKey) {
+			nudgeJuliaC(i, -1)
+		})
+		in.OnRepeat("julia"+axis+"Up", func(glfw.ModifierKey) {
+			nudgeJuliaC(i, 1)
+		})
+	}
+	in.OnRepeat("surfaceEpsilonUp", func(glfw.ModifierKey) {
+		surfaceEpsilon = mgl32.Clamp(surfaceEpsilon*1.25, 1e-6, 0.1)
+		fmt.Printf("surfaceEpsilon: %g (coarser, faster)\n", surfaceEpsilon)
+	})
+	in.OnRepeat("surfaceEpsilonDown", func(glfw.ModifierKey) {
+		surfaceEpsilon = mgl32.Clamp(surfaceEpsilon/1.25, 1e-6, 0.1)
+		fmt.Printf("surfaceEpsilon: %g (sharper, slower)\n", surfaceEpsilon)
+	})
+	// Shift+Insert/Delete move the near plane instead
+	in.OnRepeat("maxDistanceUp", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			setNearPlane(nearPlane * 1.5)
+			return
+		}
+		setMaxDistance(maxDistance * 1.5)
+	})
+	in.OnRepeat("maxDistanceDown", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			setNearPlane(nearPlane / 1.5)
+			return
+		}
+		setMaxDistance(maxDistance / 1.5)
+	})
+	// Shift+[ and Shift+] change the Mandelbox escape radius instead
+	in.OnRepeat("iterationsDown", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustEscapeRadius(-0.5)
+			return
+		}
+		maxIterations -= 5
+		if maxIterations < 1 {
+			maxIterations = 1
+		}
+		fmt.Println("maxIterations:", maxIterations)
+	})
+	in.OnRepeat("iterationsUp", func(mods glfw.ModifierKey) {
+		if mods&glfw.ModShift != 0 {
+			adjustEscapeRadius(0.5)
+			return
+		}
+		maxIterations += 5
+		if maxIterations > 1000 {
+			maxIterations = 1000
+		}
+		fmt.Println("maxIterations:", maxIterations)
+	})
+}
+
+// rotateFolds turns the fold rotation about one axis by a degree, backwards
+// if reverse is set, wrapping at a full turn.
+func rotateFolds(axis int, reverse bool) {
+	step := float32(1)
+	if reverse {
+		step = -step
+	}
+	foldRotation[axis] = float32(math.Mod(float64(foldRotation[axis]+step), 360))
+	fmt.Printf("fold rotation: %.0f, %.0f, %.0f\n", foldRotation[0], foldRotation[1], foldRotation[2])
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// The distance estimators below mirror the ones in shaders/fragment.glsl so
+// the CPU can ask how far a point is from the surface. Keep them in step
+// with the shader.
+
+// minLodIterations is the shader's floor on the iterations the level of
+// detail leaves a point.
+const minLodIterations = 8
+
+// lodIterations is the Mandelbox and Mandelbulb iteration limit the shader
+// uses for a point dist from the eye: maxIterations cut by lodFalloff, but
+// no lower than minLodIterations.
+func lodIterations(dist float64) int {
+	lod := int(float64(maxIterations) / (1 + float64(lodFalloff)*dist))
+	return max(lod, min(int(maxIterations), minLodIterations))
+}
+
+// sceneDistance estimates the distance from p to the current fractal as the
+// view drawn from eye sees it.
+func sceneDistance(p, eye mgl64.Vec3) float64 {
+	if testSphere {
+		return p.Sub(mgl64.Vec3{0, 0, -3}).Len() - 1
+	}
+	iterations := lodIterations(p.Sub(eye).Len())
+	switch fractalType {
+	case 1:
+		return mandelbulbDistance(p, iterations)
+	case 2:
+		return mengerDistance(p)
+	case 3:
+		return sierpinskiDistance(p)
+	default:
+		return mandelboxDistance(p, iterations)
+	}
+}
+
+// sceneNormal is the normalized gradient of sceneDistance at p, by central
+// differences.
+func sceneNormal(p, eye mgl64.Vec3) mgl64.Vec3 {
+	const e = 1e-4
+	return mgl64.Vec3{
+		sceneDistance(p.Add(mgl64.Vec3{e, 0, 0}), eye) - sceneDistance(p.Sub(mgl64.Vec3{e, 0, 0}), eye),
+		sceneDistance(p.Add(mgl64.Vec3{0, e, 0}), eye) - sceneDistance(p.Sub(mgl64.Vec3{0, e, 0}), eye),
+		sceneDistance(p.Add(mgl64.Vec3{0, 0, e}), eye) - sceneDistance(p.Sub(mgl64.Vec3{0, 0, e}), eye),
+	}.Normalize()
+}
+
+func mandelboxDistance(pos mgl64.Vec3, iterations int) float64 {
+	limit := float64(foldingLimit)
+	minR, fixedR := float64(minRadius), float64(fixedRadius)
+	s := float64(scale)
+	c := pos
+	if juliaMode {
+		c = mgl64.Vec3{float64(juliaC[0]), float64(juliaC[1]), float64(juliaC[2])}
+	}
+
+	z := pos
+	dr := 1.0
+	r := 0.0
+	for i := 0; i < iterations; i++ {
+		// Stop on overflow, keeping the last finite r for the estimate
+		l := z.Len()
+		if math.IsNaN(l) || math.IsInf(l, 0) {
+			break
+		}
+		r = l
+		if r > float64(escapeRadius) {
+			break
+		}
+
+		// Box fold
+		for k := range z {
+			z[k] = mgl64.Clamp(z[k], -limit, limit)*2 - z[k]
+		}
+
+		// Sphere fold
+		if r < minR {
+			f := (fixedR * fixedR) / (minR * minR)
+			z = z.Mul(f)
+			dr *= f
+		} else if r < fixedR {
+			f := (fixedR * fixedR) / (r * r)
+			z = z.Mul(f)
+			dr *= f
+		}
+
+		z = z.Mul(s).Add(c)
+		dr = dr*math.Abs(s) + 1
+	}
+
+	return 0.5 * math.Log(r) * r / dr
+}
+
+func mandelbulbDistance(pos mgl64.Vec3, iterations int) float64 {
+	const power = 8.0
+	z := pos
+	dr := 1.0
+	r := 0.0
+	for i := 0; i < iterations; i++ {
+		r = z.Len()
+		if r > 2 {
+			break
+		}
+
+		theta := math.Acos(z[2]/r) * power
+		phi := math.Atan2(z[1], z[0]) * power
+		dr = math.Pow(r, power-1)*power*dr + 1
+
+		z = mgl64.Vec3{
+			math.Sin(theta) * math.Cos(phi),
+			math.Sin(phi) * math.Sin(theta),
+			math.Cos(theta),
+		}.Mul(math.Pow(r, power)).Add(pos)
+	}
+
+	return 0.5 * math.Log(r) * r / dr
+}
+
+func mengerDistance(pos mgl64.Vec3) float64 {
+	s := float64(scale) + 1
+	offset := float64(foldingLimit)
+	rotation := foldRotationMatrix()
+	z := pos
+	dr := 1.0
+	for i := 0; i < int(mengerIterations); i++ {
+		z = mgl64.Vec3{math.Abs(z[0]), math.Abs(z[1]), math.Abs(z[2])}
+		if z[0] < z[1] {
+			z[0], z[1] = z[1], z[0]
+		}
+		if z[0] < z[2] {
+			z[0], z[2] = z[2], z[0]
+		}
+		if z[1] < z[2] {
+			z[1], z[2] = z[2], z[1]
+		}
+		z = rotation.Mul3x1(z)
+
+		z = z.Mul(s).Sub(mgl64.Vec3{offset, offset, offset}.Mul(s - 1))
+		dr *= s
+		if z[2] < -0.5*offset*(s-1) {
+			z[2] += offset * (s - 1)
+		}
+	}
+
+	d := mgl64.Vec3{math.Abs(z[0]) - 1, math.Abs(z[1]) - 1, math.Abs(z[2]) - 1}
+	outside := mgl64.Vec3{math.Max(d[0], 0), math.Max(d[1], 0), math.Max(d[2], 0)}
+	box := math.Min(math.Max(d[0], math.Max(d[1], d[2])), 0) + outside.Len()
+	return box / dr
+}
+
+func sierpinskiDistance(pos mgl64.Vec3) float64 {
+	const s = 2.0
+	rotation := foldRotationMatrix()
+	z := pos
+	dr := 1.0
+	for i := 0; i < int(sierpinskiIterations); i++ {
+		if z[0]+z[1] < 0 {
+			z[0], z[1] = -z[1], -z[0]
+		}
+		if z[0]+z[2] < 0 {
+			z[0], z[2] = -z[2], -z[0]
+		}
+		if z[1]+z[2] < 0 {
+			z[1], z[2] = -z[2], -z[1]
+		}
+		z = rotation.Mul3x1(z)
+
+		z = z.Mul(s).Sub(mgl64.Vec3{1, 1, 1}.Mul(s - 1))
+		dr *= s
+	}
+
+	return (z.Len() - 1) / dr
+}
+
+// foldRotationMatrix matches the shader's foldRotation: foldRotation's x,
+// then y, then z angle.
+func foldRotationMatrix() mgl64.Mat3 {
+	x := float64(mgl32.DegToRad(foldRotation[0]))
+	y := float64(mgl32.DegToRad(foldRotation[1]))
+	z := float64(mgl32.DegToRad(foldRotation[2]))
+	return mgl64.Rotate3DZ(z).Mul3(mgl64.Rotate3DY(y)).Mul3(mgl64.Rotate3DX(x))
+}
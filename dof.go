@@ -0,0 +1,141 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"m-box_explore/render"
+)
+
+// Texture units for the scene color and depth read by the blur pass, kept
+// clear of the HUD and palette units.
+const (
+	dofColorTextureUnit = 2
+	dofDepthTextureUnit = 3
+)
+
+// Depth of field settings. focusDistance is in world units along the ray;
+// aperture is the blur radius in pixels for a point infinitely far behind
+// the focus plane.
+var (
+	dofEnabled    bool
+	focusDistance float32 = 2.0
+	aperture      float32 = 8.0
+)
+
+var (
+	dofFragmentShaderSource = `
+		#version 330 core
+		in vec2 uv;
+		out vec4 FragColor;
+		uniform sampler2D sceneColor;
+		uniform sampler2D sceneDepth;
+		uniform float focusDistance;
+		uniform float aperture;
+		uniform float maxDistance;
+
+		#define MAX_BLUR 16.0
+		#define TAPS 48
+
+		void main() {
+			// The scene pass stores depth as a fraction of maxDistance
+			float dist = max(texture(sceneDepth, uv).r * maxDistance, 1e-4);
+			float radius = min(aperture * abs(dist - focusDistance) / dist, MAX_BLUR);
+
+			// Golden-angle spiral gives evenly spread taps over the disc
+			vec2 texel = 1.0 / vec2(textureSize(sceneColor, 0));
+			vec4 color = texture(sceneColor, uv);
+			for (int i = 1; i < TAPS; i++) {
+				float r = radius * sqrt(float(i) / float(TAPS));
+				float a = float(i) * 2.39996;
+				color += texture(sceneColor, uv + vec2(cos(a), sin(a)) * r * texel);
+			}
+			FragColor = color / float(TAPS);
+		}
+	` + "\x00"
+)
+
+// depthOfField renders the scene into an off-screen color and depth target
+// and then draws it to the window, blurring each pixel by how far it lies
+// from the focus plane.
+type depthOfField struct {
+	program      *render.Program
+	quad         *render.Quad
+	fbo          uint32
+	colorTexture uint32
+	depthTexture uint32
+	width        int32
+	height       int32
+}
+
+func newDepthOfField(quad *render.Quad) (*depthOfField, error) {
+	program, err := render.NewProgram(render.QuadVertexShader, dofFragmentShaderSource)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &depthOfField{program: program, quad: quad}
+
+	gl.GenFramebuffers(1, &d.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, d.fbo)
+
+	gl.GenTextures(1, &d.colorTexture)
+	gl.BindTexture(gl.TEXTURE_2D, d.colorTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, d.colorTexture, 0)
+
+	gl.GenTextures(1, &d.depthTexture)
+	gl.BindTexture(gl.TEXTURE_2D, d.depthTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, d.depthTexture, 0)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	return d, nil
+}
+
+// resize reallocates the off-screen target when the framebuffer size changes.
+func (d *depthOfField) resize(w, h int32) {
+	if w == d.width && h == d.height {
+		return
+	}
+	d.width, d.height = w, h
+
+	gl.BindTexture(gl.TEXTURE_2D, d.colorTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, w, h, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+
+	gl.BindTexture(gl.TEXTURE_2D, d.depthTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT24, w, h, 0, gl.DEPTH_COMPONENT, gl.UNSIGNED_INT, nil)
+}
+
+// render runs drawScene into the off-screen target and composites it onto
+// the framebuffer that was bound beforehand, with the blur applied.
+func (d *depthOfField) render(drawScene func()) {
+	d.resize(fbWidth, fbHeight)
+
+	var target int32
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &target)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, d.fbo)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawScene()
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(target))
+
+	gl.ActiveTexture(gl.TEXTURE0 + dofColorTextureUnit)
+	gl.BindTexture(gl.TEXTURE_2D, d.colorTexture)
+	gl.ActiveTexture(gl.TEXTURE0 + dofDepthTextureUnit)
+	gl.BindTexture(gl.TEXTURE_2D, d.depthTexture)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	d.program.Use()
+	gl.Uniform1i(d.program.Uniform("sceneColor"), dofColorTextureUnit)
+	gl.Uniform1i(d.program.Uniform("sceneDepth"), dofDepthTextureUnit)
+	gl.Uniform1f(d.program.Uniform("focusDistance"), focusDistance)
+	gl.Uniform1f(d.program.Uniform("aperture"), aperture)
+	gl.Uniform1f(d.program.Uniform("maxDistance"), maxDistance)
+
+	d.quad.Draw()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"image"
+	imagedraw "image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+)
+
+// environmentTextureUnit holds the cubemap, clear of the palette and
+// post-processing units.
+const environmentTextureUnit = 6
+
+// cubemapFaces are the file names looked for in the -cubemap directory, in
+// GL face order: +X, -X, +Y, -Y, +Z, -Z.
+var cubemapFaces = [6]string{"px.png", "nx.png", "py.png", "ny.png", "pz.png", "nz.png"}
+
+var (
+	// cubemapDir is the directory given with -cubemap
+	cubemapDir string
+
+	// environmentLoaded is set once the cubemap is uploaded. Until then, or
+	// without one, the background is the sky gradient.
+	environmentLoaded bool
+
+	// environmentTexture is the cubemap bound to environmentTextureUnit, or
+	// 0 before one is uploaded
+	environmentTexture uint32
+)
+
+// readCubemap decodes six square face images of equal size, in GL face
+// order. It doesn't touch GL, so it can run off the main thread.
+func readCubemap(paths [6]string) ([6]*image.RGBA, error) {
+	var faces [6]*image.RGBA
+	for i, path := range paths {
+		img, err := loadImage(path)
+		if err != nil {
+			return faces, err
+		}
+		b := img.Bounds()
+		if b.Dx() != b.Dy() {
+			return faces, fmt.Errorf("%s: cubemap faces must be square, got %dx%d", path, b.Dx(), b.Dy())
+		}
+		if i > 0 && b.Size() != faces[0].Bounds().Size() {
+			return faces, fmt.Errorf("%s: face size %v doesn't match %v", path, b.Size(), faces[0].Bounds().Size())
+		}
+
+		rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		imagedraw.Draw(rgba, rgba.Bounds(), img, b.Min, imagedraw.Src)
+		faces[i] = rgba
+	}
+	return faces, nil
+}
+
+// loadCubemap reads six face images, in GL face order, into a cubemap
+// texture bound to environmentTextureUnit, replacing environmentTexture.
+func loadCubemap(paths [6]string) (uint32, error) {
+	faces, err := readCubemap(paths)
+	if err != nil {
+		return 0, err
+	}
+	return uploadCubemap(faces), nil
+}
+
+// uploadCubemap stores faces from readCubemap in a cubemap texture bound to
+// environmentTextureUnit, replacing environmentTexture, and returns it.
+func uploadCubemap(faces [6]*image.RGBA) uint32 {
+	deleteEnvironment()
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.ActiveTexture(gl.TEXTURE0 + environmentTextureUnit)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, texture)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for i, face := range faces {
+		size := int32(face.Bounds().Dx())
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(i), 0, gl.RGBA8, size, size, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(face.Pix))
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	environmentTexture = texture
+	environmentLoaded = true
+	return texture
+}
+
+// deleteEnvironment frees environmentTexture, if there is one, and goes back
+// to the sky gradient.
+func deleteEnvironment() {
+	if environmentTexture != 0 {
+		gl.DeleteTextures(1, &environmentTexture)
+		environmentTexture = 0
+	}
+	environmentLoaded = false
+}
+
+// cubemapPaths lists the face files in dir.
+func cubemapPaths(dir string) [6]string {
+	var paths [6]string
+	for i, name := range cubemapFaces {
+		paths[i] = filepath.Join(dir, name)
+	}
+	return paths
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"m-box_explore/input"
+	"m-box_explore/render"
+	"math"
+	"time"
+)
+
+// passes are the post-processing passes wrapped around the scene and the
+// overlays drawn over it each frame.
+type passes struct {
+	overlay  *hud
+	dof      *depthOfField
+	glow     *bloom
+	smoother *fxaa
+	scaler   *resolutionScaler
+	accum    *accumulator
+	bounds   *boundsBox
+	overview *minimap
+}
+
+func newPasses(quad *render.Quad) (*passes, error) {
+	var p passes
+	var err error
+	if p.overlay, err = newHUD(); err != nil {
+		return nil, fmt.Errorf("failed to initialize HUD: %v", err)
+	}
+	if p.dof, err = newDepthOfField(quad); err != nil {
+		return nil, fmt.Errorf("failed to initialize depth of field: %v", err)
+	}
+	if p.glow, err = newBloom(quad); err != nil {
+		return nil, fmt.Errorf("failed to initialize bloom: %v", err)
+	}
+	if p.smoother, err = newFXAA(quad); err != nil {
+		return nil, fmt.Errorf("failed to initialize FXAA: %v", err)
+	}
+
+	copier, err := render.NewCopy(quad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize copy pass: %v", err)
+	}
+	p.scaler = newResolutionScaler(copier)
+	if p.accum, err = newAccumulator(quad, copier); err != nil {
+		return nil, fmt.Errorf("failed to initialize accumulation: %v", err)
+	}
+	if p.bounds, err = newBoundsBox(); err != nil {
+		return nil, fmt.Errorf("failed to initialize bounds box: %v", err)
+	}
+	if p.overview, err = newMinimap(copier); err != nil {
+		return nil, fmt.Errorf("failed to initialize minimap: %v", err)
+	}
+	return &p, nil
+}
+
+// run draws frames until the window is closed.
+func run(window *glfw.Window, renderer *render.Renderer, cam *camera.Camera, in *input.Handler, p *passes, assets *assetLoader) {
+	lastFrame = glfw.GetTime()
+	for !window.ShouldClose() {
+		currentFrame := glfw.GetTime()
+		deltaTime = float32(currentFrame - lastFrame)
+		lastFrame = currentFrame
+
+		if reloadShaders {
+			reloadProgram(renderer)
+			reloadShaders = false
+		}
+
+		processInput(in, cam)
+		processGamepad(cam)
+		draw(window, renderer, cam, p, assets)
+
+		if powerSave && idle(in, cam, p.accum) {
+			glfw.WaitEventsTimeout(idleTimeout)
+			// Time spent asleep isn't frame time; counting it would make
+			// the first movement after waking jump
+			lastFrame = glfw.GetTime()
+		} else {
+			glfw.PollEvents()
+		}
+
+		if fpsCap > 0 {
+			limitFrameRate(currentFrame)
+		}
+	}
+}
+
+// idle reports whether the next frame would look the same as the last one
+// unless some input arrives: nothing animating, playing back or being
+// captured, the camera at rest, no key held and no gamepad to poll.
+func idle(in *input.Handler, cam *camera.Camera, accum *accumulator) bool {
+	animating := animateScale && !animationPaused
+	return !animating && !playing && !recording && timelapseInterval < 0 &&
+		cam.Idle() && !in.AnyHeld() && !glfw.Joystick1.Present() &&
+		accum.converged()
+}
+
+// limitFrameRate sleeps out the rest of the frame that began at frameStart so
+// the loop runs no faster than fpsCap.
+func limitFrameRate(frameStart float64) {
+	remaining := 1/fpsCap - (glfw.GetTime() - frameStart)
+	if remaining > 0 {
+		time.Sleep(time.Duration(remaining * float64(time.Second)))
+	}
+}
+
+// draw advances the camera and animation by deltaTime, then renders the
+// scene through the enabled passes, draws the overlays and presents the
+// frame.
+func draw(window *glfw.Window, renderer *render.Renderer, cam *camera.Camera, p *passes, assets *assetLoader) {
+	if assets.poll() {
+		p.accum.reset()
+	}
+
+	fpsFrames++
+	fpsElapsed += deltaTime
+	if fpsElapsed >= 0.5 {
+		fps = float32(fpsFrames) / fpsElapsed
+		fpsFrames = 0
+		fpsElapsed = 0
+		// Shown even with the HUD hidden, and in the task bar
+		window.SetTitle(fmt.Sprintf("%s - %.1f FPS - scale %.3f - %d iterations", title, fps, scale, maxIterations))
+	}
+
+	from := cam.Position
+	cam.Update(deltaTime)
+	if collisionEnabled && !orbitMode {
+		resolveCollision(cam, from)
+	}
+	if orbitMode {
+		// Smoothed mouse look turns the camera during Update
+		cam.Orbit(orbitTarget, orbitRadius)
+	}
+	updatePath(cam)
+
+	if !animationPaused {
+		animationTime += float64(deltaTime)
+	}
+
+	if animateScale {
+		scale = mgl32.Clamp(baseScale+amplitude*float32(math.Sin(animationTime*float64(freq))), minScale, maxScale)
+	}
+
+	clearAlpha := float32(1)
+	if transparent {
+		clearAlpha = 0
+	}
+	gl.ClearColor(backgroundColor[0], backgroundColor[1], backgroundColor[2], clearAlpha)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	// The parameters are gathered inside the closure since the passes
+	// around it change the size and jitter
+	drawScene := func() { renderer.Draw(sceneParams(cam)) }
+	if dofEnabled {
+		drawSharp := drawScene
+		drawScene = func() { p.dof.render(drawSharp) }
+	}
+	if bloomEnabled {
+		drawUnlit := drawScene
+		drawScene = func() { p.glow.render(drawUnlit) }
+	}
+	if fxaaEnabled {
+		drawJagged := drawScene
+		drawScene = func() { p.smoother.render(drawJagged) }
+	}
+	if accumulate {
+		drawFrame := drawScene
+		drawScene = func() { p.accum.render(cam, drawFrame) }
+	}
+	if adaptiveResolution {
+		p.scaler.render(drawScene)
+	} else {
+		drawScene()
+	}
+	checkGLError("rendering scene")
+
+	// Stereo views are offset from the camera, so the box is drawn for mono
+	// only
+	if showBounds && !stereo && !anaglyph {
+		p.bounds.draw(cam)
+		checkGLError("drawing bounds")
+	}
+
+	if showMinimap {
+		p.overview.draw(renderer, cam)
+		checkGLError("drawing minimap")
+	}
+
+	if showHUD {
+		lines := []string{
+			fmt.Sprintf("FPS: %.1f", fps),
+			fmt.Sprintf("Pos: %.3f, %.3f, %.3f", cam.Position[0], cam.Position[1], cam.Position[2]),
+			fmt.Sprintf("Yaw: %.1f  Pitch: %.1f", cam.Yaw, cam.Pitch),
+			"Fractal: " + fractalNames[fractalType],
+			scaleLine(),
+			fmt.Sprintf("Iterations: %d", maxIterations),
+			fmt.Sprintf("Resolution: %.0f%%", resolutionScale*100),
+		}
+		if juliaMode {
+			lines = append(lines, fmt.Sprintf("Julia C: %.3f, %.3f, %.3f", juliaC[0], juliaC[1], juliaC[2]))
+		}
+		if picked {
+			lines = append(lines, fmt.Sprintf("Picked: %.3f, %.3f, %.3f", pickedPoint[0], pickedPoint[1], pickedPoint[2]))
+		}
+		if debugOffset != (mgl32.Vec3{}) || debugAxis >= 0 {
+			line := fmt.Sprintf("Offset: %.2f, %.2f, %.2f", debugOffset[0], debugOffset[1], debugOffset[2])
+			if debugAxis >= 0 {
+				line += " (axis " + axisNames[debugAxis] + ")"
+			}
+			lines = append(lines, line)
+		}
+		p.overlay.draw(lines)
+		checkGLError("drawing HUD")
+	}
+
+	captureFrame(lastFrame)
+	window.SwapBuffers()
+}
+
+// fractalNames are the HUD names of the fractal types, indexed by fractalType.
+var fractalNames = [numFractalTypes]string{"Mandelbox", "Mandelbulb", "Menger sponge", "Sierpinski tetrahedron"}
+
+// scaleLine is the HUD's scale readout, flagged when scale is pinned at the
+// edge of the stable range.
+func scaleLine() string {
+	line := fmt.Sprintf("Scale: %.3f", scale)
+	if scale <= minScale || scale >= maxScale {
+		line += " (limit)"
+	}
+	return line
+}
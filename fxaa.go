@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"m-box_explore/render"
+)
+
+// fxaaTextureUnit holds the FXAA pass's input, after the accumulation
+// units.
+const fxaaTextureUnit = 9
+
+// FXAA settings. Pixels whose neighborhood varies in luminance by less than
+// fxaaThreshold of its brightest are left alone; lower values smooth more
+// edges and blur more texture.
+var (
+	fxaaEnabled   bool
+	fxaaThreshold float32 = 0.125
+)
+
+var fxaaShaderSource = `
+	#version 330 core
+	in vec2 uv;
+	out vec4 FragColor;
+	uniform sampler2D image;
+	uniform vec2 texel; // one pixel in texture coordinates
+	uniform float edgeThreshold;
+
+	float luma(vec3 c) {
+		return dot(c, vec3(0.299, 0.587, 0.114));
+	}
+
+	void main() {
+		vec4 center = texture(image, uv);
+		float lumaM = luma(center.rgb);
+		float lumaNW = luma(texture(image, uv + vec2(-1.0, 1.0) * texel).rgb);
+		float lumaNE = luma(texture(image, uv + vec2(1.0, 1.0) * texel).rgb);
+		float lumaSW = luma(texture(image, uv + vec2(-1.0, -1.0) * texel).rgb);
+		float lumaSE = luma(texture(image, uv + vec2(1.0, -1.0) * texel).rgb);
+		float lumaMin = min(lumaM, min(min(lumaNW, lumaNE), min(lumaSW, lumaSE)));
+		float lumaMax = max(lumaM, max(max(lumaNW, lumaNE), max(lumaSW, lumaSE)));
+		if (lumaMax - lumaMin < max(edgeThreshold * lumaMax, 1.0 / 32.0)) {
+			FragColor = center;
+			return;
+		}
+
+		// Blur along the edge, which runs across the luminance gradient,
+		// over at most 8 pixels
+		vec2 dir = vec2((lumaSW + lumaSE) - (lumaNW + lumaNE), (lumaNW + lumaSW) - (lumaNE + lumaSE));
+		float reduce = max((lumaNW + lumaNE + lumaSW + lumaSE) * 0.03125, 1.0 / 128.0);
+		dir = clamp(dir / (min(abs(dir.x), abs(dir.y)) + reduce), -8.0, 8.0) * texel;
+
+		vec3 inner = 0.5 * (texture(image, uv - dir / 6.0).rgb + texture(image, uv + dir / 6.0).rgb);
+		vec3 outer = 0.5 * inner + 0.25 * (texture(image, uv - dir * 0.5).rgb + texture(image, uv + dir * 0.5).rgb);
+		// The wider blur can cross onto another edge; fall back to the
+		// narrow one when it leaves the neighborhood's range
+		float lumaOuter = luma(outer);
+		vec3 color = (lumaOuter < lumaMin || lumaOuter > lumaMax) ? inner : outer;
+		FragColor = vec4(color, center.a);
+	}
+` + "\x00"
+
+// fxaa smooths jagged edges found from luminance contrast in a single
+// full-screen pass over the rendered frame, a far cheaper way to antialias
+// than marching extra rays per pixel.
+type fxaa struct {
+	program *render.Program
+	quad    *render.Quad
+
+	sceneFBO     uint32
+	sceneTexture uint32
+	width        int32
+	height       int32
+}
+
+func newFXAA(quad *render.Quad) (*fxaa, error) {
+	program, err := render.NewProgram(render.QuadVertexShader, fxaaShaderSource)
+	if err != nil {
+		return nil, err
+	}
+	f := &fxaa{program: program, quad: quad}
+	f.sceneFBO, f.sceneTexture = newColorTarget()
+	return f, nil
+}
+
+func (f *fxaa) resize(w, h int32) {
+	if w == f.width && h == f.height {
+		return
+	}
+	f.width, f.height = w, h
+
+	gl.BindTexture(gl.TEXTURE_2D, f.sceneTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, w, h, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+}
+
+// render runs drawScene into the pass's scene target and draws it, edges
+// smoothed, onto the framebuffer that was bound beforehand.
+func (f *fxaa) render(drawScene func()) {
+	f.resize(fbWidth, fbHeight)
+
+	var target int32
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &target)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.sceneFBO)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	drawScene()
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(target))
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+	f.program.Use()
+	gl.ActiveTexture(gl.TEXTURE0 + fxaaTextureUnit)
+	gl.BindTexture(gl.TEXTURE_2D, f.sceneTexture)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.Uniform1i(f.program.Uniform("image"), fxaaTextureUnit)
+	gl.Uniform2f(f.program.Uniform("texel"), 1/float32(fbWidth), 1/float32(fbHeight))
+	gl.Uniform1f(f.program.Uniform("edgeThreshold"), fxaaThreshold)
+	f.quad.Draw()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+)
+
+const (
+	gamepadDeadzone  = 0.15  // stick deflection ignored as drift
+	gamepadLookSpeed = 120.0 // degrees per second at full deflection
+)
+
+// processGamepad polls the first joystick and maps it onto the camera: left
+// stick strafes, right stick looks, bumpers roll and triggers change scale.
+// Axes are read by their standard gamepad positions, and anything the
+// controller doesn't report is treated as centred.
+func processGamepad(cam *camera.Camera) {
+	joy := glfw.Joystick1
+	if !joy.Present() {
+		return
+	}
+
+	axes := joy.GetAxes()
+	axis := func(a glfw.GamepadAxis) float32 {
+		if int(a) >= len(axes) {
+			return 0
+		}
+		v := axes[a]
+		if v > -gamepadDeadzone && v < gamepadDeadzone {
+			return 0
+		}
+		return v
+	}
+	// Triggers rest at -1 and are fully pulled at 1
+	trigger := func(a glfw.GamepadAxis) float32 {
+		if int(a) >= len(axes) {
+			return 0
+		}
+		v := (axes[a] + 1) / 2
+		if v < gamepadDeadzone {
+			return 0
+		}
+		return v
+	}
+
+	buttons := joy.GetButtons()
+	pressed := func(b glfw.GamepadButton) bool {
+		return int(b) < len(buttons) && buttons[b] == glfw.Press
+	}
+
+	look := gamepadLookSpeed * deltaTime
+	cam.Rotate(axis(glfw.AxisRightX)*look, -axis(glfw.AxisRightY)*look)
+
+	if orbitMode {
+		cam.Orbit(orbitTarget, orbitRadius)
+	} else {
+		// Stick up reports negative Y
+		if y := -axis(glfw.AxisLeftY); y > 0 {
+			cam.ProcessKeyboard(camera.Forward, y*deltaTime)
+		} else if y < 0 {
+			cam.ProcessKeyboard(camera.Backward, -y*deltaTime)
+		}
+		if x := axis(glfw.AxisLeftX); x > 0 {
+			cam.ProcessKeyboard(camera.Right, x*deltaTime)
+		} else if x < 0 {
+			cam.ProcessKeyboard(camera.Left, -x*deltaTime)
+		}
+	}
+
+	if pressed(glfw.ButtonLeftBumper) {
+		cam.ProcessRoll(-1, deltaTime)
+	}
+	if pressed(glfw.ButtonRightBumper) {
+		cam.ProcessRoll(1, deltaTime)
+	}
+
+	scale += (trigger(glfw.AxisRightTrigger) - trigger(glfw.AxisLeftTrigger)) * scaleSpeed * deltaTime
+	scale = mgl32.Clamp(scale, minScale, maxScale)
+}
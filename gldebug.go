@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"log"
+	"unsafe"
+)
+
+// glDebug turns on error checking and driver debug output, set with
+// -glDebug. It's off by default since glGetError stalls the pipeline.
+var glDebug bool
+
+// checkGLError logs any pending OpenGL errors along with tag, which says
+// where they were noticed.
+func checkGLError(tag string) {
+	if !glDebug {
+		return
+	}
+
+	// Errors queue up, one per flag; cap the loop in case the context is
+	// lost and keeps reporting
+	for i := 0; i < 8; i++ {
+		code := gl.GetError()
+		if code == gl.NO_ERROR {
+			return
+		}
+		log.Printf("OpenGL error %s (0x%x) after %s\n", glErrorName(code), code, tag)
+	}
+}
+
+func glErrorName(code uint32) string {
+	switch code {
+	case gl.INVALID_ENUM:
+		return "INVALID_ENUM"
+	case gl.INVALID_VALUE:
+		return "INVALID_VALUE"
+	case gl.INVALID_OPERATION:
+		return "INVALID_OPERATION"
+	case gl.INVALID_FRAMEBUFFER_OPERATION:
+		return "INVALID_FRAMEBUFFER_OPERATION"
+	case gl.OUT_OF_MEMORY:
+		return "OUT_OF_MEMORY"
+	default:
+		return "unknown error"
+	}
+}
+
+// enableDebugOutput logs driver messages through the KHR_debug callback,
+// which names the failing call and often the reason. Drivers only report
+// much in a debug context, which main requests when glDebug is set.
+func enableDebugOutput() {
+	if !glfw.ExtensionSupported("GL_KHR_debug") {
+		log.Println("GL_KHR_debug not supported, falling back to glGetError checks")
+		return
+	}
+
+	gl.Enable(gl.DEBUG_OUTPUT)
+	// Report from inside the offending call so the stack is meaningful
+	gl.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+	gl.DebugMessageCallback(func(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+		if severity == gl.DEBUG_SEVERITY_NOTIFICATION {
+			return
+		}
+		log.Printf("OpenGL debug (source 0x%x, type 0x%x, severity 0x%x): %s\n", source, gltype, severity, message)
+	}, nil)
+}
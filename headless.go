@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"image"
+	_ "image/png"
+	"m-box_explore/camera"
+	"m-box_explore/render"
+	"os"
+)
+
+// goldenTolerance is the largest per-channel difference from the reference
+// image that -golden accepts, to allow for differences between drivers.
+const goldenTolerance = 8
+
+// goldenPath is the reference image for -golden, which -update rewrites
+// rather than checks.
+var (
+	goldenPath   string
+	updateGolden bool
+)
+
+// goldenConfig is the small, fixed view -golden renders: the whole default
+// Mandelbox from outside.
+func goldenConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Width, cfg.Height = 64, 64
+	cfg.Position = mgl32.Vec3{0, 0, 8}
+	cfg.MaxIterations = 20
+	return cfg
+}
+
+// renderOnce renders cfg in an invisible window's context and returns the
+// pixels. It owns GLFW for its duration, so it must run on the main thread
+// and not while a window is open. Settings cfg doesn't cover keep their
+// current values, which -golden requires to be the defaults.
+func renderOnce(cfg Config) (*image.RGBA, error) {
+	if err := glfw.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize glfw: %v", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	// The frame goes to an off-screen target, so the window only has to
+	// provide a context
+	window, err := glfw.CreateWindow(1, 1, title, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window: %v", err)
+	}
+	defer window.Destroy()
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize OpenGL: %v", err)
+	}
+	renderer, err := initOpenGL(readShaders())
+	if err != nil {
+		return nil, err
+	}
+	defer renderer.Delete()
+	uploadPalette(defaultPalette)
+
+	cfg.apply()
+	cam := camera.New(startPos)
+	cam.Yaw, cam.Pitch = startYaw, startPitch
+	cam.UpdateFront()
+
+	target, err := newOffscreen(int32(cfg.Width), int32(cfg.Height))
+	if err != nil {
+		return nil, err
+	}
+	defer target.delete()
+
+	return target.render(renderer, sceneParams(cam)), nil
+}
+
+// checkGolden renders goldenConfig and compares it with the reference image
+// at path, or with updateGolden set, writes it there as the new reference.
+func checkGolden(path string) error {
+	img, err := renderOnce(goldenConfig())
+	if err != nil {
+		return err
+	}
+
+	if updateGolden {
+		if err := render.WritePNG(path, img); err != nil {
+			return err
+		}
+		fmt.Println("wrote reference image", path)
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no reference image at %s; create it with -update", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ref, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	if ref.Bounds() != img.Bounds() {
+		return fmt.Errorf("reference is %v, render is %v", ref.Bounds().Size(), img.Bounds().Size())
+	}
+
+	bad := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r0, g0, b0, a0 := ref.At(x, y).RGBA()
+			r1, g1, b1, a1 := img.At(x, y).RGBA()
+			for _, d := range [4][2]uint32{{r0, r1}, {g0, g1}, {b0, b1}, {a0, a1}} {
+				// RGBA returns 16-bit channels
+				if diff(d[0]>>8, d[1]>>8) > goldenTolerance {
+					bad++
+					break
+				}
+			}
+		}
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d of %d pixels differ from %s", bad, b.Dx()*b.Dy(), path)
+	}
+	fmt.Println("render matches", path)
+	return nil
+}
+
+func diff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
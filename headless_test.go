@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mainThread runs functions on the main thread, which GLFW must be called
+// from. init locks the main goroutine to it and TestMain serves the channel.
+var mainThread = make(chan func())
+
+func TestMain(m *testing.M) {
+	done := make(chan int)
+	go func() {
+		done <- m.Run()
+	}()
+	for {
+		select {
+		case f := <-mainThread:
+			f()
+		case code := <-done:
+			os.Exit(code)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("no display to create an OpenGL context on")
+	}
+
+	errc := make(chan error, 1)
+	mainThread <- func() {
+		errc <- checkGolden(filepath.Join("testdata", "golden.png"))
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTransparentPremultiplied checks that a transparent render with
+// supersampled edges comes back premultiplied, as image.RGBA requires.
+func TestTransparentPremultiplied(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("no display to create an OpenGL context on")
+	}
+
+	oldTransparent, oldSamples := transparent, aaSamples
+	transparent, aaSamples = true, 2
+	defer func() { transparent, aaSamples = oldTransparent, oldSamples }()
+
+	type result struct {
+		img *image.RGBA
+		err error
+	}
+	resc := make(chan result, 1)
+	mainThread <- func() {
+		img, err := renderOnce(goldenConfig())
+		resc <- result{img, err}
+	}
+	res := <-resc
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	edges := 0
+	pix := res.img.Pix
+	for i := 0; i < len(pix); i += 4 {
+		a := pix[i+3]
+		if a > 0 && a < 255 {
+			edges++
+		}
+		if pix[i] > a || pix[i+1] > a || pix[i+2] > a {
+			t.Fatalf("pixel %d is %v, with a channel above its alpha", i/4, pix[i:i+4])
+		}
+	}
+	if edges == 0 {
+		t.Error("no partly covered pixels; the view doesn't test the edges")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"m-box_explore/render"
+)
+
+const (
+	hudWidth   = 320
+	hudHeight  = 148
+	hudMargin  = 8
+	hudPadding = 6
+)
+
+var (
+	hudVertexShaderSource = `
+		#version 330 core
+		layout (location = 0) in vec2 aPos;
+		uniform vec4 rect; // top-left and bottom-right corners in NDC
+		out vec2 uv;
+		void main() {
+			uv = aPos;
+			gl_Position = vec4(mix(rect.xy, rect.zw, aPos), 0.0, 1.0);
+		}
+	` + "\x00"
+
+	hudFragmentShaderSource = `
+		#version 330 core
+		in vec2 uv;
+		out vec4 FragColor;
+		uniform sampler2D text;
+		void main() {
+			FragColor = texture(text, uv);
+		}
+	` + "\x00"
+)
+
+// hud renders lines of text into a CPU-side image with a bitmap font and
+// blits it as a textured quad in the top-left corner of the window.
+type hud struct {
+	program *render.Program
+	vao     uint32
+	vbo     uint32
+	texture uint32
+	img     *image.RGBA
+}
+
+func newHUD() (*hud, error) {
+	program, err := render.NewProgram(hudVertexShaderSource, hudFragmentShaderSource)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hud{
+		program: program,
+		img:     image.NewRGBA(image.Rect(0, 0, hudWidth, hudHeight)),
+	}
+
+	vertices := []float32{
+		0.0, 0.0,
+		1.0, 0.0,
+		0.0, 1.0,
+		1.0, 1.0,
+	}
+
+	gl.GenVertexArrays(1, &h.vao)
+	gl.BindVertexArray(h.vao)
+
+	gl.GenBuffers(1, &h.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, h.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 2*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+
+	gl.GenTextures(1, &h.texture)
+	gl.BindTexture(gl.TEXTURE_2D, h.texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, hudWidth, hudHeight, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+
+	return h, nil
+}
+
+func (h *hud) draw(lines []string) {
+	// Translucent black backing so the text stays readable over the fractal
+	for i := 0; i < len(h.img.Pix); i += 4 {
+		h.img.Pix[i+0] = 0
+		h.img.Pix[i+1] = 0
+		h.img.Pix[i+2] = 0
+		h.img.Pix[i+3] = 160
+	}
+
+	face := basicfont.Face7x13
+	d := &font.Drawer{Dst: h.img, Src: image.White, Face: face}
+	for i, line := range lines {
+		d.Dot = fixed.P(hudPadding, hudPadding+face.Ascent+i*face.Height)
+		d.DrawString(line)
+	}
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, h.texture)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, hudWidth, hudHeight, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(h.img.Pix))
+
+	// Convert the pixel-space box in the top-left corner to NDC, enlarged
+	// by the content scale so it stays legible on HiDPI displays
+	x0 := -1 + 2*hudMargin*contentScale/float32(fbWidth)
+	y0 := 1 - 2*hudMargin*contentScale/float32(fbHeight)
+	x1 := x0 + 2*hudWidth*contentScale/float32(fbWidth)
+	y1 := y0 - 2*hudHeight*contentScale/float32(fbHeight)
+
+	h.program.Use()
+	gl.Uniform4f(h.program.Uniform("rect"), x0, y0, x1, y1)
+	gl.Uniform1i(h.program.Uniform("text"), 0)
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.BindVertexArray(h.vao)
+	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+	gl.Disable(gl.BLEND)
+}
@@ -0,0 +1,190 @@
+// Package input routes GLFW keyboard and mouse events to actions bound by the
+// application.
+package input
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// KeyFunc handles a key event; mods are the modifier keys held at the time.
+type KeyFunc func(mods glfw.ModifierKey)
+
+// Handler owns a window's key and cursor callbacks. Functions are attached to
+// named actions, and a bindings map decides which key triggers each action.
+// Actions bound with OnPress fire once per press; actions bound with
+// OnRepeat also fire on key repeat. Mouse movement is reported as offsets,
+// and only while the cursor is captured, except while a button with a drag
+// function is held, when it goes to that function instead. Mouse button
+// presses go to click functions with the cursor position.
+type Handler struct {
+	window      *glfw.Window
+	bindings    map[string]glfw.Key
+	press       map[string]KeyFunc
+	repeat      map[string]KeyFunc
+	onAnyKey    func()
+	onMouseMove func(dx, dy float64)
+	drag        map[glfw.MouseButton]func(dx, dy float64)
+	click       map[glfw.MouseButton]func(x, y float64)
+
+	captured   bool
+	firstMouse bool
+	lastX      float64
+	lastY      float64
+}
+
+// NewHandler installs the handler's callbacks on window. bindings maps each
+// action name to its key.
+func NewHandler(window *glfw.Window, bindings map[string]glfw.Key) *Handler {
+	h := &Handler{
+		window:     window,
+		bindings:   bindings,
+		press:      make(map[string]KeyFunc),
+		repeat:     make(map[string]KeyFunc),
+		drag:       make(map[glfw.MouseButton]func(dx, dy float64)),
+		click:      make(map[glfw.MouseButton]func(x, y float64)),
+		firstMouse: true,
+	}
+	window.SetKeyCallback(h.keyCallback)
+	window.SetCursorPosCallback(h.cursorPosCallback)
+	window.SetMouseButtonCallback(h.mouseButtonCallback)
+	return h
+}
+
+// OnPress sets the function called once each time action's key is pressed.
+func (h *Handler) OnPress(action string, fn KeyFunc) {
+	h.press[action] = fn
+}
+
+// OnRepeat sets the function called when action's key is pressed and again
+// on each key repeat while it is held.
+func (h *Handler) OnRepeat(action string, fn KeyFunc) {
+	h.repeat[action] = fn
+}
+
+// OnAnyKey sets a function called for every key press and repeat, bound or
+// not, before any action runs.
+func (h *Handler) OnAnyKey(fn func()) {
+	h.onAnyKey = fn
+}
+
+// OnMouseMove sets the function given cursor offsets in pixels, with y
+// increasing upwards.
+func (h *Handler) OnMouseMove(fn func(dx, dy float64)) {
+	h.onMouseMove = fn
+}
+
+// OnDrag sets the function given cursor offsets while button is held, in
+// place of OnMouseMove's, whether or not the cursor is captured.
+func (h *Handler) OnDrag(button glfw.MouseButton, fn func(dx, dy float64)) {
+	h.drag[button] = fn
+}
+
+// OnClick sets the function called when button is pressed, given the cursor
+// position as CursorPos returns it. While the cursor is captured it is
+// hidden in the middle of the window, so that is the position given.
+func (h *Handler) OnClick(button glfw.MouseButton, fn func(x, y float64)) {
+	h.click[button] = fn
+}
+
+// Held reports whether the key bound to action is currently down, for
+// movement polled each frame.
+func (h *Handler) Held(action string) bool {
+	key, ok := h.bindings[action]
+	return ok && h.window.GetKey(key) == glfw.Press
+}
+
+// AnyHeld reports whether any bound key is down.
+func (h *Handler) AnyHeld() bool {
+	for _, key := range h.bindings {
+		if h.window.GetKey(key) == glfw.Press {
+			return true
+		}
+	}
+	return false
+}
+
+// CursorPos returns the cursor position as a fraction of the window size,
+// from 0,0 at the bottom-left corner to 1,1 at the top-right.
+func (h *Handler) CursorPos() (x, y float64) {
+	cx, cy := h.window.GetCursorPos()
+	w, ht := h.window.GetSize()
+	if w == 0 || ht == 0 {
+		return 0.5, 0.5
+	}
+	return cx / float64(w), 1 - cy/float64(ht)
+}
+
+// Captured reports whether the cursor is captured for mouse look.
+func (h *Handler) Captured() bool {
+	return h.captured
+}
+
+// SetCaptured hides and locks the cursor for mouse look, or releases it.
+func (h *Handler) SetCaptured(captured bool) {
+	h.captured = captured
+	if captured {
+		h.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	} else {
+		h.window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+	}
+}
+
+func (h *Handler) keyCallback(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if h.onAnyKey != nil && action != glfw.Release {
+		h.onAnyKey()
+	}
+
+	for name, bound := range h.bindings {
+		if bound != key {
+			continue
+		}
+
+		if action == glfw.Press {
+			if fn, ok := h.press[name]; ok {
+				fn(mods)
+			}
+		}
+
+		if action == glfw.Press || action == glfw.Repeat {
+			if fn, ok := h.repeat[name]; ok {
+				fn(mods)
+			}
+		}
+	}
+}
+
+func (h *Handler) cursorPosCallback(window *glfw.Window, xpos float64, ypos float64) {
+	if h.firstMouse {
+		h.lastX = xpos
+		h.lastY = ypos
+		h.firstMouse = false
+	}
+
+	xoffset := xpos - h.lastX
+	yoffset := h.lastY - ypos // Reversed since y-coordinates go from bottom to top
+	h.lastX = xpos
+	h.lastY = ypos
+
+	for button, fn := range h.drag {
+		if window.GetMouseButton(button) == glfw.Press {
+			fn(xoffset, yoffset)
+			return
+		}
+	}
+
+	if h.captured && h.onMouseMove != nil {
+		h.onMouseMove(xoffset, yoffset)
+	}
+}
+
+func (h *Handler) mouseButtonCallback(window *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	fn, ok := h.click[button]
+	if !ok || action != glfw.Press {
+		return
+	}
+	if h.captured {
+		fn(0.5, 0.5)
+		return
+	}
+	fn(h.CursorPos())
+}
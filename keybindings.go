@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"os"
+)
+
+const keyBindingsFile = "keybindings.json"
+
+// defaultKeyBindings maps each action to its key. keybindings.json only
+// needs to list the actions it changes.
+var defaultKeyBindings = map[string]glfw.Key{
+	"forward":     glfw.KeyW,
+	"back":        glfw.KeyS,
+	"strafeLeft":  glfw.KeyA,
+	"strafeRight": glfw.KeyD,
+	"ascend":      glfw.KeyRightShift,
+	"descend":     glfw.KeyRightControl,
+	"sprint":      glfw.KeyLeftShift,
+	"crawl":       glfw.KeyLeftControl,
+	"rollLeft":    glfw.KeyQ,
+	"rollRight":   glfw.KeyE,
+	"scaleUp":     glfw.KeyEqual,
+	"scaleDown":   glfw.KeyMinus,
+
+	"captureMouse":       glfw.KeyEscape,
+	"sensitivityDown":    glfw.KeyLeft,
+	"sensitivityUp":      glfw.KeyRight,
+	"screenshot":         glfw.KeyF12,
+	"antiAliasing":       glfw.KeyF2,
+	"toggleHUD":          glfw.KeyH,
+	"reloadShaders":      glfw.KeyR,
+	"orbitMode":          glfw.KeyC,
+	"walkMode":           glfw.KeyF1,
+	"collision":          glfw.KeySlash,
+	"colorMode":          glfw.KeyV,
+	"juliaMode":          glfw.KeyY,
+	"fractalType":        glfw.KeyTab,
+	"rotX":               glfw.KeyEnter,
+	"rotY":               glfw.KeyBackspace,
+	"rotZ":               glfw.KeyScrollLock,
+	"animateScale":       glfw.KeyT,
+	"pauseAnimation":     glfw.KeySpace,
+	"stepAnimation":      glfw.KeyBackslash,
+	"fullscreen":         glfw.KeyF11,
+	"recordPath":         glfw.KeyF6,
+	"playPath":           glfw.KeyF7,
+	"vsync":              glfw.KeyF8,
+	"showDepth":          glfw.KeyF9,
+	"crosshair":          glfw.KeyP,
+	"showBounds":         glfw.Key0,
+	"minimap":            glfw.KeyKPDecimal,
+	"keyLight":           glfw.KeyKP7,
+	"fillLight":          glfw.KeyKP5,
+	"ambientLight":       glfw.KeyKP3,
+	"stereo":             glfw.KeyF3,
+	"anaglyph":           glfw.KeyGraveAccent,
+	"adaptiveResolution": glfw.KeyF4,
+	"bloom":              glfw.KeyF10,
+	"depthOfField":       glfw.KeyB,
+	"resetAccumulation":  glfw.KeyEnd,
+	"addBookmark":        glfw.KeyF5,
+	"printView":          glfw.KeyKPEnter,
+	"reset":              glfw.KeyHome,
+	"bookmark1":          glfw.Key1,
+	"bookmark2":          glfw.Key2,
+	"bookmark3":          glfw.Key3,
+	"bookmark4":          glfw.Key4,
+	"bookmark5":          glfw.Key5,
+	"bookmark6":          glfw.Key6,
+	"bookmark7":          glfw.Key7,
+	"bookmark8":          glfw.Key8,
+	"bookmark9":          glfw.Key9,
+
+	"debugZoomDown":      glfw.KeyZ,
+	"debugZoomUp":        glfw.KeyX,
+	"debugUp":            glfw.KeyI,
+	"debugDown":          glfw.KeyK,
+	"debugLeft":          glfw.KeyJ,
+	"debugRight":         glfw.KeyL,
+	"debugNear":          glfw.KeyU,
+	"debugFar":           glfw.KeyO,
+	"debugAxis":          glfw.KeyLeftAlt,
+	"foldingLimitDown":   glfw.KeyComma,
+	"foldingLimitUp":     glfw.KeyPeriod,
+	"epsilonFactorDown":  glfw.KeySemicolon,
+	"epsilonFactorUp":    glfw.KeyApostrophe,
+	"fog":                glfw.KeyF,
+	"focusNearer":        glfw.KeyN,
+	"focusFarther":       glfw.KeyM,
+	"reflectivity":       glfw.KeyG,
+	"juliaXDown":         glfw.KeyKP4,
+	"juliaXUp":           glfw.KeyKP6,
+	"juliaYDown":         glfw.KeyKP2,
+	"juliaYUp":           glfw.KeyKP8,
+	"juliaZDown":         glfw.KeyKP1,
+	"juliaZUp":           glfw.KeyKP9,
+	"surfaceEpsilonUp":   glfw.KeyPageUp,
+	"surfaceEpsilonDown": glfw.KeyPageDown,
+	"iterationsDown":     glfw.KeyLeftBracket,
+	"iterationsUp":       glfw.KeyRightBracket,
+	"maxDistanceUp":      glfw.KeyInsert,
+	"maxDistanceDown":    glfw.KeyDelete,
+	"bloomUp":            glfw.KeyUp,
+	"bloomDown":          glfw.KeyDown,
+	"colorScaleUp":       glfw.KeyKPAdd,
+	"colorScaleDown":     glfw.KeyKPSubtract,
+	"hueShiftUp":         glfw.KeyKPMultiply,
+	"hueShiftDown":       glfw.KeyKPDivide,
+	"reflections":        glfw.KeyKP0,
+	"shadowSteps":        glfw.KeyPause,
+}
+
+// loadKeyBindings returns the default bindings with any overrides from the
+// JSON object at path, which maps action names to GLFW key codes. A missing
+// file leaves the defaults unchanged.
+func loadKeyBindings(path string) (map[string]glfw.Key, error) {
+	bindings := make(map[string]glfw.Key, len(defaultKeyBindings))
+	for action, key := range defaultKeyBindings {
+		bindings[action] = key
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return bindings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides map[string]glfw.Key
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	for action, key := range overrides {
+		if _, ok := bindings[action]; !ok {
+			return nil, fmt.Errorf("%s: unknown action %q", path, action)
+		}
+		bindings[action] = key
+	}
+	return bindings, nil
+}
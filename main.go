@@ -1,132 +1,282 @@
 package main
 
 import (
+	_ "embed"
+	"flag"
 	"fmt"
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 	"log"
-	"math"
+	"m-box_explore/camera"
+	"m-box_explore/input"
+	"m-box_explore/render"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
 const (
-	width  = 1280
-	height = 720
-	title  = "3D Mandelbox Fractal Explorer"
+	title = "3D Mandelbox Fractal Explorer"
+
+	scaleSpeed = 1.0 // scale units per second
+
+	// Beyond this range the Mandelbox iteration blows up to Inf/NaN within a
+	// few steps and little is left to see
+	minScale = -4.0
+	maxScale = 4.0
+
+	numFractalTypes = 4 // 0 = Mandelbox, 1 = Mandelbulb, 2 = Menger sponge, 3 = Sierpinski
+	numColorModes   = 4 // 0 = iteration count, 1 = orbit trap, 2 = surface normal, 3 = escape time
+	numColorCurves  = 2 // 0 = linear, 1 = gamma
 )
 
 var (
-	vertexShaderSource = `
-		#version 330 core
-		layout (location = 0) in vec3 aPos;
-		void main() {
-			gl_Position = vec4(aPos.x, aPos.y, aPos.z, 1.0);
-		}
-	` + "\x00"
-
-	fragmentShaderSource = `
-		#version 330 core
-		out vec4 FragColor;
-		
-		uniform vec3 cameraPos;
-		uniform vec3 cameraFront;
-		uniform vec3 cameraUp;
-		uniform float scale;
-		uniform int maxIterations;
-		uniform vec2 resolution;
-		uniform mat4 projection;
-
-		uniform float debugZoom;
-		uniform vec3 debugOffset;
-
-		#define EPSILON 0.001
-		#define MAX_DISTANCE 100.0
-		#define MAX_STEPS 200
-
-		float mandelboxDE(vec3 pos) {
-			vec3 z = pos;
-			float dr = 1.0;
-			float r = 0.0;
-
-			for (int i = 0; i < maxIterations; i++) {
-				r = length(z);
-				if (r > 6.0) break; // tweakable
-
-				// Box fold
-				z = clamp(z, -1.0, 1.0) * 2.0 - z;
-
-				// Sphere fold
-				if (r < 0.5) {
-					z *= 4.0;
-					dr *= 4.0;
-				} else if (r < 1.0) {
-					z /= r * r;
-					dr /= r * r;
-				}
-
-				z = z * scale + pos;
-				dr = dr * abs(scale) + 1.0;
-			}
+	//go:embed shaders/vertex.glsl
+	vertexShaderSource string
 
-			return 0.5 * log(r) * r / dr;
-		}
+	//go:embed shaders/fragment.glsl
+	fragmentShaderSource string
+)
 
-		vec3 hsv2rgb(vec3 c) {
-			vec4 K = vec4(1.0, 2.0 / 3.0, 1.0 / 3.0, 3.0);
-			vec3 p = abs(fract(c.xxx + K.xyz) * 6.0 - K.www);
-			return c.z * mix(K.xxx, clamp(p - K.xxx, 0.0, 1.0), c.y);
-		}
+// Initial window size, from Config.
+var (
+	width  int
+	height int
+)
 
-		void main() {
-			vec2 uv = (gl_FragCoord.xy / resolution.xy) * 2.0 - 1.0;
-			vec4 rayDir = projection * vec4(uv, -1.0, 1.0);
-			rayDir = normalize(vec4(rayDir.xyz, 0.0));
-
-			float t = 0.0;
-			for (int i = 0; i < MAX_STEPS; i++) {
-				vec3 p = cameraPos + t * rayDir.xyz;
-				float d = mandelboxDE(p);
-				if (d < EPSILON) {
-					float hue = float(i) / 100.0;
-					float sat = 0.8;
-					float val = 1.0 - float(i) / 100.0;
-					vec3 color = hsv2rgb(vec3(hue, sat, val));
-					FragColor = vec4(color, 1.0);
-					return;
-				}
-				t += d;
-					if (t > MAX_DISTANCE) break;
-			}
-			FragColor = vec4(0.0, 0.0, 0.0, 1.0);
-		}
-	` + "\x00"
+var (
+	scale         float32
+	maxIterations int32
+	projection    mgl32.Mat4
+	debugZoom     float32 = 1.0
+	debugOffset   mgl32.Vec3
+	testSphere    bool
+	fbWidth       int32
+	fbHeight      int32
+	lastFrame     float64
+	deltaTime     float32
+	showHUD       bool = true
+	fps           float32
+	fpsFrames     int
+	fpsElapsed    float32
+	aaSamples     int32   = 1 // rays per pixel along each axis
+	fov           float32     // vertical, in degrees
+	reloadShaders bool
+	vsync         bool    = true
+	fpsCap        float64 // frames per second, 0 for uncapped
+	showDepth     bool
+	stepHeatmap   bool
+)
+
+// The camera's initial Sensitivity, from Config, and MouseSmoothing, set
+// with -mouseSmoothing.
+var (
+	mouseSensitivity float32
+	mouseSmoothing   float64
 )
 
+// With powerSave on, the loop sleeps until an input event, or at most
+// idleTimeout seconds, whenever there is nothing new to draw.
+const idleTimeout = 0.5
+
+var powerSave = true
+
+// Hardware multisampling of the window. The fractal is drawn as a single
+// full-screen quad, so MSAA has no geometry edges to smooth there; the
+// shader's own supersampling (aaSamples) covers the fractal, and MSAA only
+// antialiases the line and HUD overlays drawn on top. The sample count is
+// fixed when the window is created; msaaEnabled switches it on and off.
 var (
-	camera           mgl32.Vec3
-	cameraFront      mgl32.Vec3
-	cameraUp         mgl32.Vec3
-	yaw              float32 = -90.0
-	pitch            float32
-	lastX            float64
-	lastY            float64
-	firstMouse       bool    = true
-	scale            float32 = 2.0
-	maxIterations    int32   = 100
-	mouseSensitivity float32 = 0.05
-	captureMouse     bool    = false
-	projection       mgl32.Mat4
-	debugZoom        float32 = 1.0
-	debugOffset      mgl32.Vec3
+	msaaSamples = 4
+	msaaEnabled = true
 )
 
+// Starting view, from Config or the saved session.
+var (
+	startPos   mgl32.Vec3
+	startYaw   float32
+	startPitch float32
+	startRoll  float32
+	startWalk  bool
+)
+
+// Side-by-side stereo for phone VR viewers, and red-cyan anaglyph, which
+// puts the left eye in the red channel and the right eye in green and blue.
+// eyeSeparation is the distance between the two eye positions in world
+// units.
+var (
+	stereo        bool
+	anaglyph      bool
+	anaglyphSwap  bool    // for glasses with the red filter on the right
+	eyeSeparation float32 = 0.05
+)
+
+// backgroundColor clears the window before the scene is drawn. With
+// transparent set, the background is cleared to alpha 0 instead and the
+// fractal leaves alpha 0 wherever its rays miss, so screenshots and -render
+// output can be composited over other images.
+var (
+	backgroundColor mgl32.Vec3
+	transparent     bool
+)
+
+var (
+	showCrosshair  bool
+	crosshairColor = mgl32.Vec3{1, 1, 1}
+)
+
+// Ray-march budget. Raising epsilonFactor loosens the hit threshold with
+// distance travelled, which speeds up rendering at the cost of detail far
+// from the camera; 0 uses a fixed threshold everywhere.
+var (
+	maxSteps       int32   = 200
+	surfaceEpsilon float32 = 0.001
+	epsilonFactor  float32 = 0.5
+	maxDistance    float32 = 100.0 // also the projection's far plane
+
+	// lodFalloff likewise cuts the Mandelbox and Mandelbulb iterations with
+	// distance from the camera; 0 always runs maxIterations
+	lodFalloff float32
+)
+
+// contentScale is the ratio of framebuffer pixels to logical pixels on the
+// window's monitor, 2 on a typical HiDPI display. Overlays sized in pixels
+// are scaled by it so they keep their size on screen.
+var contentScale float32 = 1
+
+// nearPlane is the projection's near clip distance. Only rasterized
+// overlays are clipped by it; rays are built from the field of view alone.
+var nearPlane float32 = 0.1
+
+var (
+	orbitMode   bool
+	orbitTarget mgl32.Vec3
+	orbitRadius float32
+)
+
+// Window geometry saved before entering fullscreen so it can be restored.
+var (
+	windowedX      int
+	windowedY      int
+	windowedWidth  int
+	windowedHeight int
+)
+
+var (
+	lightPos               = mgl32.Vec3{5, 5, 5}
+	shadowSoftness float32 = 16.0
+	lightDir               = mgl32.Vec3{1, 1, 1}.Normalize()
+	aoStrength     float32 = 0.5 // 0 disables ambient occlusion
+	fogColor               = mgl32.Vec3{0.02, 0.03, 0.08}
+	fogDensity     float32 = 0.05
+	reflectivity   float32 = 0.2
+	skyTop                 = mgl32.Vec3{0.01, 0.01, 0.03}
+	skyBottom              = mgl32.Vec3{0.06, 0.07, 0.12}
+	sunIntensity   float32 = 0.5
+	colorMode      int32   = 3   // smooth escape time, which the IFS fractals show as march steps
+	colorScale     float32 = 1.0 // palette repeats per full march-step range
+	ditherStrength float32 = 1.0 // in 8-bit steps, 0 for none
+	gamma          float32 = 2.2 // display gamma the linear output is encoded for
+	hueShift       float32
+
+	// With the gamma curve, the iteration count coloring indexes the palette
+	// by its fraction of maxSteps raised to colorGamma, spreading out the
+	// low counts when below 1 and the high ones when above
+	colorCurve int32
+	colorGamma float32 = 0.5
+)
+
+// Quality of the secondary rays: reflection bounces traced per pixel and
+// march steps per shadow ray. Fewer of either is faster on slow GPUs.
+var (
+	maxReflections  int32 = 1
+	softShadowSteps int32 = 64
+)
+
+// Lights besides the main one at lightPos: a shadowless directional fill
+// light from the other side and a flat ambient term, so surfaces the main
+// light can't reach aren't left black. Each can be switched off.
+var (
+	keyLightEnabled  = true
+	fillLightEnabled = true
+	ambientEnabled   = true
+	fillLightDir     = mgl32.Vec3{-1, 0.5, -0.5}.Normalize()
+	fillLightColor   = mgl32.Vec3{0.25, 0.3, 0.4}
+	ambientColor     = mgl32.Vec3{0.06, 0.06, 0.08}
+)
+
+var (
+	fractalType  int32
+	foldingLimit float32
+	minRadius    float32 = 0.5
+	fixedRadius  float32 = 1.0
+	escapeRadius float32 = 6.0
+
+	// The sponge shrinks by its scale every iteration, so a handful is
+	// enough and many more overflow.
+	mengerIterations int32 = 8
+
+	// The tetrahedron halves in size every fold
+	sierpinskiIterations int32 = 14
+
+	// foldRotation turns the sponge and tetrahedron between folds, in degrees
+	// about x, y and z
+	foldRotation mgl32.Vec3
+
+	juliaMode bool
+	juliaC    = mgl32.Vec3{0.5, 0.5, 0.5}
+)
+
+var (
+	animateScale bool
+	baseScale    float32
+	amplitude    float32 = 0.5 // scale swing either side of baseScale
+	freq         float32 = 0.5 // radians per second
+)
+
+// Animation runs on its own clock so it can be paused and stepped frame by
+// frame independently of wall time. The shader sees it as time.
+var (
+	animationTime   float64
+	animationPaused bool
+)
+
+const animationStep = 1.0 / 30.0 // seconds advanced per step while paused
+
 func init() {
 	runtime.LockOSThread()
 }
 
 func main() {
+	cfg := parseFlags()
+	cfg.apply()
+
+	if goldenPath != "" {
+		// Every other setting changes the image, so the check only runs
+		// with the defaults
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name != "golden" && f.Name != "update" && f.Name != "glDebug" {
+				log.Fatalf("-golden can't be combined with -%s\n", f.Name)
+			}
+		})
+		if err := checkGolden(goldenPath); err != nil {
+			log.Fatalln("golden image check failed:", err)
+		}
+		return
+	}
+
+	// Offline renders, exports and benchmarks only go by their flags
+	resume := !noResume && renderPath == "" && exportPath == "" && !benchmark
+	if resume {
+		if err := loadSession(sessionFile); err != nil {
+			log.Println("failed to load session, starting fresh:", err)
+		}
+	}
+
+	// Read the asset files while the window and context come up
+	assets := loadAssets()
+
 	if err := glfw.Init(); err != nil {
 		log.Fatalln("failed to initialize glfw:", err)
 	}
@@ -137,6 +287,18 @@ func main() {
 	glfw.WindowHint(glfw.ContextVersionMinor, 3)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	if renderPath != "" || exportPath != "" {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	}
+	if glDebug {
+		glfw.WindowHint(glfw.OpenGLDebugContext, glfw.True)
+	}
+	glfw.WindowHint(glfw.Samples, msaaSamples)
+	// Size the window in logical units, as macOS already does
+	glfw.WindowHint(glfw.ScaleToMonitor, glfw.True)
+	if transparent {
+		glfw.WindowHint(glfw.TransparentFramebuffer, glfw.True)
+	}
 
 	window, err := glfw.CreateWindow(width, height, title, nil, nil)
 	if err != nil {
@@ -144,9 +306,19 @@ func main() {
 	}
 
 	window.MakeContextCurrent()
-	window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
-	window.SetCursorPosCallback(mouseMoveCallback)
-	window.SetKeyCallback(keyCallback)
+	if vsync {
+		glfw.SwapInterval(1)
+	} else {
+		glfw.SwapInterval(0)
+	}
+	// width and height are in screen coordinates; on HiDPI displays the
+	// framebuffer has more pixels than that
+	fbw, fbh := window.GetFramebufferSize()
+	fbWidth, fbHeight = int32(fbw), int32(fbh)
+	contentScale, _ = window.GetContentScale()
+	window.SetContentScaleCallback(func(w *glfw.Window, x float32, y float32) {
+		contentScale = x
+	})
 
 	if err := gl.Init(); err != nil {
 		log.Fatalln("failed to initialize OpenGL:", err)
@@ -155,225 +327,325 @@ func main() {
 	version := gl.GoStr(gl.GetString(gl.VERSION))
 	fmt.Println("OpenGL version", version)
 
-	program, vao := initOpenGL()
-
-	initCamera()
-
-	for !window.ShouldClose() {
-		draw(window, program, vao)
+	if glDebug {
+		enableDebugOutput()
 	}
-}
 
-func initOpenGL() (uint32, uint32) {
-	vertexShader, err := compileShader(vertexShaderSource, gl.VERTEX_SHADER)
+	renderer, err := initOpenGL(assets.shaders())
 	if err != nil {
-		log.Fatalln("failed to compile vertex shader:", err)
+		log.Fatalln("failed to initialize OpenGL resources:", err)
 	}
+	defer renderer.Delete()
+	defer deleteEnvironment()
 
-	fragmentShader, err := compileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		log.Fatalln("failed to compile fragment shader:", err)
+	paletteTexture = uploadPalette(defaultPalette)
+
+	// Frames written to disk must have the real assets, and a preset's
+	// palette must not be replaced by the -palette one arriving later
+	if renderPath != "" || exportPath != "" || benchmark || presetName != "" {
+		assets.wait()
 	}
 
-	program := gl.CreateProgram()
-	gl.AttachShader(program, vertexShader)
-	gl.AttachShader(program, fragmentShader)
-	gl.LinkProgram(program)
+	cam := initCamera()
+	if presetName != "" {
+		p, err := loadPreset(presetName)
+		if err == nil {
+			err = p.apply(cam)
+		}
+		if err != nil {
+			log.Fatalln("failed to load preset:", err)
+		}
+	}
 
-	var status int32
-	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
-		str := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(str))
-		log.Fatalln("failed to link program:", str)
+	if renderPath != "" {
+		if err := renderOffline(renderPath, renderer, cam); err != nil {
+			log.Fatalln("failed to render:", err)
+		}
+		return
 	}
 
-	gl.DeleteShader(vertexShader)
-	gl.DeleteShader(fragmentShader)
+	if exportPath != "" {
+		if err := exportPathFrames(exportPath, exportDir, renderer, cam); err != nil {
+			log.Fatalln("failed to export path:", err)
+		}
+		return
+	}
 
-	vertices := []float32{
-		-1.0, -1.0, 0.0,
-		1.0, -1.0, 0.0,
-		-1.0, 1.0, 0.0,
-		1.0, 1.0, 0.0,
+	if benchmark {
+		runBenchmark(window, renderer, cam)
+		return
 	}
 
-	var vao uint32
-	gl.GenVertexArrays(1, &vao)
-	gl.BindVertexArray(vao)
+	framePasses, err := newPasses(renderer.Quad())
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-	var vbo uint32
-	gl.GenBuffers(1, &vbo)
-	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+	window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	bindings, err := loadKeyBindings(keyBindingsFile)
+	if err != nil {
+		log.Println("failed to load key bindings, using defaults:", err)
+		bindings = defaultKeyBindings
+	}
+	in := input.NewHandler(window, bindings)
+	bindKeys(in, window, cam, framePasses.accum)
+	window.SetFramebufferSizeCallback(framebufferSizeCallback)
+	window.SetScrollCallback(scrollCallback)
 
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(0)
+	run(window, renderer, cam, in, framePasses, assets)
 
-	return program, vao
+	if resume {
+		if err := saveSession(sessionFile, window, cam); err != nil {
+			log.Println("failed to save session:", err)
+		}
+	}
 }
 
-func initCamera() {
-	camera = mgl32.Vec3{0, 0, 0} // Move camera closer
-	cameraFront = mgl32.Vec3{0, 0, -1}
-	cameraUp = mgl32.Vec3{0, 1, 0}
-
-	aspectRatio := float32(width) / float32(height)
-	fov := float32(90.0) // FOV
-	projection = mgl32.Perspective(mgl32.DegToRad(fov), aspectRatio, 0.1, 100.0)
+// parseFlags returns DefaultConfig overridden from the command line. The
+// settings Config doesn't cover are set directly.
+func parseFlags() Config {
+	cfg := DefaultConfig()
+	iterations := flag.Int("iterations", int(cfg.MaxIterations), "maximum fractal iterations")
+	scaleFlag := flag.Float64("scale", float64(cfg.Scale), "fractal scale")
+	fractal := flag.Int("fractal", int(cfg.FractalType), "fractal type (0 = Mandelbox, 1 = Mandelbulb, 2 = Menger sponge, 3 = Sierpinski tetrahedron)")
+	mengerIters := flag.Int("mengerIterations", int(mengerIterations), "Menger sponge fold iterations")
+	sierpinskiIters := flag.Int("sierpinskiIterations", int(sierpinskiIterations), "Sierpinski tetrahedron fold iterations")
+	foldRot := flag.String("foldRotation", "0,0,0", "rotation between folds of the Menger sponge and Sierpinski tetrahedron, in degrees about x,y,z")
+	flag.IntVar(&cfg.Width, "width", cfg.Width, "initial window width")
+	flag.IntVar(&cfg.Height, "height", cfg.Height, "initial window height")
+	fovFlag := flag.Float64("fov", float64(cfg.FOV), "vertical field of view in degrees")
+	sensitivity := flag.Float64("sensitivity", float64(cfg.Sensitivity), "mouse look degrees per pixel")
+	flag.StringVar(&renderPath, "render", "", "render a single frame to this PNG and exit")
+	flag.IntVar(&renderScale, "renderScale", renderScale, "resolution multiplier for -render")
+	flag.IntVar(&msaaSamples, "msaa", msaaSamples, "multisample count for overlays drawn over the fractal, 0 for none")
+	flag.StringVar(&exportPath, "export", "", "render this recorded camera path to numbered PNGs and exit")
+	flag.StringVar(&exportDir, "out", exportDir, "directory for -export frames")
+	flag.Float64Var(&exportFPS, "exportFPS", exportFPS, "frame rate of -export")
+	flag.IntVar(&exportWidth, "exportWidth", 0, "width of -export frames, 0 for the window width")
+	flag.IntVar(&exportHeight, "exportHeight", 0, "height of -export frames, 0 for the window height")
+	flag.StringVar(&goldenPath, "golden", "", "render a small fixed view with the default settings, compare it with this reference PNG and exit")
+	flag.BoolVar(&updateGolden, "update", false, "with -golden, write the reference image instead of comparing with it")
+	flag.Float64Var(&fpsCap, "fpsCap", 0, "limit the frame rate, 0 for uncapped")
+	flag.Float64Var(&timelapseInterval, "timelapse", timelapseInterval, "save a screenshot every this many seconds, 0 for every frame, negative for never")
+	flag.StringVar(&timelapseDir, "timelapseDir", timelapseDir, "directory for -timelapse frames")
+	flag.StringVar(&palettePath, "palette", "", "file of RGB color stops to use as the palette")
+	flag.StringVar(&presetName, "preset", "", "start from this preset in the presets directory, named without .json")
+	flag.BoolVar(&benchmark, "benchmark", false, "time a fixed camera path with vsync off, print frame stats and exit")
+	flag.Float64Var(&targetFPS, "targetFPS", targetFPS, "frame rate adaptive resolution aims for")
+	flag.BoolVar(&noResume, "no-resume", false, "start fresh instead of restoring the session saved on exit")
+	flag.BoolVar(&powerSave, "powerSave", powerSave, "stop redrawing while nothing on screen is changing")
+	flag.BoolVar(&glDebug, "glDebug", false, "check for OpenGL errors and log driver debug messages")
+	flag.BoolVar(&testSphere, "testSphere", false, "replace the fractal with a unit sphere at 0,0,-3 to check the view isn't stretched")
+	flag.Float64Var(&mouseSmoothing, "mouseSmoothing", 0, "mouse look smoothing in [0,0.9], 0 for none")
+	eyeSep := flag.Float64("eyeSeparation", float64(eyeSeparation), "distance between the eyes in stereo mode")
+	flag.StringVar(&cubemapDir, "cubemap", "", "directory of px/nx/py/ny/pz/nz.png faces to use as the environment")
+	skyTopFlag := flag.String("skyTop", "0.01,0.01,0.03", "sky color overhead as r,g,b in 0-1")
+	skyBottomFlag := flag.String("skyBottom", "0.06,0.07,0.12", "sky color at the horizon and below as r,g,b in 0-1")
+	crosshair := flag.String("crosshairColor", "1,1,1", "crosshair color as r,g,b in 0-1")
+	background := flag.String("backgroundColor", "0,0,0", "window clear color as r,g,b in 0-1")
+	flag.BoolVar(&fxaaEnabled, "fxaa", false, "smooth edges with an FXAA pass, much cheaper than extra rays per pixel")
+	edgeThreshold := flag.Float64("fxaaThreshold", float64(fxaaThreshold), "smallest local contrast, relative to brightness, FXAA treats as an edge")
+	dither := flag.Float64("dither", float64(ditherStrength), "dithering strength in 8-bit steps, 0 to disable")
+	gammaFlag := flag.Float64("gamma", float64(gamma), "display gamma, 1 to write linear colors")
+	flag.BoolVar(&transparent, "transparent", false, "leave the background transparent where no surface is hit")
+	pos := flag.String("pos", fmt.Sprintf("%g,%g,%g", cfg.Position[0], cfg.Position[1], cfg.Position[2]), "starting camera position as x,y,z")
+	yaw := flag.Float64("yaw", float64(cfg.Yaw), "starting camera yaw in degrees")
+	pitch := flag.Float64("pitch", float64(cfg.Pitch), "starting camera pitch in degrees")
+	folding := flag.Float64("foldingLimit", float64(cfg.FoldingLimit), "Mandelbox box fold limit")
+	steps := flag.Int("maxSteps", int(maxSteps), "maximum ray-march steps per ray, fewer to speed up slow hardware")
+	lod := flag.Float64("lodFalloff", float64(lodFalloff), "how fast Mandelbox and Mandelbulb iterations drop with distance, 0 for none")
+	near := flag.Float64("near", float64(nearPlane), "projection near plane distance")
+	far := flag.Float64("far", float64(maxDistance), "projection far plane and ray-march cutoff distance")
+	flag.Parse()
+
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		log.Fatalln("window size must be positive")
+	}
+	if *fovFlag < 20 || *fovFlag > 120 {
+		log.Fatalln("fov must be between 20 and 120")
+	}
+	if *sensitivity <= 0 {
+		log.Fatalln("sensitivity must be positive")
+	}
+	if renderScale < 1 {
+		log.Fatalln("renderScale must be at least 1")
+	}
+	if exportFPS <= 0 {
+		log.Fatalln("exportFPS must be positive")
+	}
+	if exportWidth < 0 || exportHeight < 0 {
+		log.Fatalln("export size must not be negative")
+	}
+	if msaaSamples < 0 {
+		log.Fatalln("msaa must not be negative")
+	}
+	if mouseSmoothing < 0 || mouseSmoothing > 0.9 {
+		log.Fatalln("mouseSmoothing must be between 0 and 0.9")
+	}
+	if targetFPS <= 0 {
+		log.Fatalln("targetFPS must be positive")
+	}
+	if *near <= 0 || *far <= *near {
+		log.Fatalln("near must be positive and less than far")
+	}
+	if *iterations < 1 || *iterations > 1000 {
+		log.Fatalln("iterations must be between 1 and 1000")
+	}
+	if *fractal < 0 || *fractal >= numFractalTypes {
+		log.Fatalf("unknown fractal type %d\n", *fractal)
+	}
+	if *mengerIters < 1 || *mengerIters > 20 {
+		log.Fatalln("mengerIterations must be between 1 and 20")
+	}
+	if *sierpinskiIters < 1 || *sierpinskiIters > 30 {
+		log.Fatalln("sierpinskiIterations must be between 1 and 30")
+	}
+	if *steps < 1 || *steps > 5000 {
+		log.Fatalln("maxSteps must be between 1 and 5000")
+	}
+	color, err := parseVec3(*crosshair)
+	if err != nil {
+		log.Fatalln("invalid crosshairColor:", err)
+	}
+	crosshairColor = color
+	if skyTop, err = parseVec3(*skyTopFlag); err != nil {
+		log.Fatalln("invalid skyTop:", err)
+	}
+	if skyBottom, err = parseVec3(*skyBottomFlag); err != nil {
+		log.Fatalln("invalid skyBottom:", err)
+	}
+	if backgroundColor, err = parseVec3(*background); err != nil {
+		log.Fatalln("invalid backgroundColor:", err)
+	}
+	eyeSeparation = float32(*eyeSep)
+	ditherStrength = float32(*dither)
+	fxaaThreshold = mgl32.Clamp(float32(*edgeThreshold), 0.01, 1)
+	gamma = mgl32.Clamp(float32(*gammaFlag), 0.5, 4)
+	if foldRotation, err = parseVec3(*foldRot); err != nil {
+		log.Fatalln("invalid foldRotation:", err)
+	}
+	if cfg.Position, err = parseVec3(*pos); err != nil {
+		log.Fatalln("invalid pos:", err)
+	}
+	cfg.Yaw = float32(*yaw)
+	cfg.Pitch = float32(*pitch)
+	cfg.FOV = float32(*fovFlag)
+	cfg.Sensitivity = float32(*sensitivity)
+	cfg.MaxIterations = int32(*iterations)
+	cfg.Scale = float32(*scaleFlag)
+	cfg.FractalType = int32(*fractal)
+	cfg.FoldingLimit = float32(*folding)
+
+	mengerIterations = int32(*mengerIters)
+	sierpinskiIterations = int32(*sierpinskiIters)
+	maxSteps = int32(*steps)
+	nearPlane = float32(*near)
+	maxDistance = float32(*far)
+	lodFalloff = max(float32(*lod), 0)
+	return cfg
 }
 
-func compileShader(source string, shaderType uint32) (uint32, error) {
-	shader := gl.CreateShader(shaderType)
-	csources, free := gl.Strs(source)
-	gl.ShaderSource(shader, 1, csources, nil)
-	free()
-	gl.CompileShader(shader)
-
-	var status int32
-	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
-		return 0, fmt.Errorf("failed to compile shader: %v", log)
+// parseVec3 reads three comma-separated numbers, such as "1,0.5,0".
+func parseVec3(s string) (mgl32.Vec3, error) {
+	var v mgl32.Vec3
+	fields := strings.Split(s, ",")
+	if len(fields) != 3 {
+		return v, fmt.Errorf("expected 3 comma-separated values, got %q", s)
 	}
-
-	return shader, nil
+	for i, field := range fields {
+		f, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+		if err != nil {
+			return v, err
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
 }
 
-func draw(window *glfw.Window, program uint32, vao uint32) {
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-	gl.UseProgram(program)
-
-	cameraPosUniform := gl.GetUniformLocation(program, gl.Str("cameraPos\x00"))
-	gl.Uniform3fv(cameraPosUniform, 1, &camera[0])
-
-	cameraFrontUniform := gl.GetUniformLocation(program, gl.Str("cameraFront\x00"))
-	gl.Uniform3fv(cameraFrontUniform, 1, &cameraFront[0])
-
-	cameraUpUniform := gl.GetUniformLocation(program, gl.Str("cameraUp\x00"))
-	gl.Uniform3fv(cameraUpUniform, 1, &cameraUp[0])
-
-	scaleUniform := gl.GetUniformLocation(program, gl.Str("scale\x00"))
-	gl.Uniform1f(scaleUniform, scale)
-
-	maxIterationsUniform := gl.GetUniformLocation(program, gl.Str("maxIterations\x00"))
-	gl.Uniform1i(maxIterationsUniform, maxIterations)
+// initOpenGL builds the fractal renderer from src and sets up the context's
+// global state.
+func initOpenGL(src shaderSources) (*render.Renderer, error) {
+	renderer, err := render.NewRenderer(src.vertex, src.fragment)
+	if err != nil {
+		return nil, err
+	}
 
-	resolutionUniform := gl.GetUniformLocation(program, gl.Str("resolution\x00"))
-	gl.Uniform2f(resolutionUniform, float32(width), float32(height))
+	setMultisample(msaaEnabled)
+	checkGLError("initOpenGL")
+	return renderer, nil
+}
 
-	projectionUniform := gl.GetUniformLocation(program, gl.Str("projection\x00"))
-	gl.UniformMatrix4fv(projectionUniform, 1, false, &projection[0])
+func setMultisample(enabled bool) {
+	msaaEnabled = enabled
+	if enabled {
+		gl.Enable(gl.MULTISAMPLE)
+	} else {
+		gl.Disable(gl.MULTISAMPLE)
+	}
+}
 
-	gl.BindVertexArray(vao)
-	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+func initCamera() *camera.Camera {
+	cam := camera.New(startPos)
+	cam.Yaw = startYaw
+	cam.Pitch = startPitch
+	cam.Roll = startRoll
+	cam.Walk = startWalk
+	cam.UpdateFront()
+	cam.Sensitivity = mouseSensitivity
+	cam.MouseSmoothing = float32(mouseSmoothing)
+
+	updateProjection()
+	return cam
+}
 
-	debugZoomUniform := gl.GetUniformLocation(program, gl.Str("debugZoom\x00"))
-	gl.Uniform1f(debugZoomUniform, debugZoom)
+// updateProjection rebuilds the projection the overlays and picking use,
+// after the field of view or the framebuffer size changes.
+func updateProjection() {
+	projection = render.Perspective(fov, fbWidth, fbHeight, nearPlane, maxDistance)
+}
 
-	debugOffsetUniform := gl.GetUniformLocation(program, gl.Str("debugOffset\x00"))
-	gl.Uniform3fv(debugOffsetUniform, 1, &debugOffset[0])
+func framebufferSizeCallback(window *glfw.Window, w int, h int) {
+	if w == 0 || h == 0 {
+		return // minimized
+	}
 
-	window.SwapBuffers()
-	glfw.PollEvents()
+	fbWidth = int32(w)
+	fbHeight = int32(h)
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+	updateProjection()
 }
 
-func mouseMoveCallback(window *glfw.Window, xpos float64, ypos float64) {
-	if !captureMouse {
+// toggleFullscreen switches between windowed mode and borderless fullscreen
+// at the primary monitor's current video mode. The framebuffer size callback
+// picks up the new resolution.
+func toggleFullscreen(window *glfw.Window) {
+	if window.GetMonitor() != nil {
+		window.SetMonitor(nil, windowedX, windowedY, windowedWidth, windowedHeight, glfw.DontCare)
 		return
 	}
 
-	if firstMouse {
-		lastX = xpos
-		lastY = ypos
-		firstMouse = false
-	}
-
-	xoffset := xpos - lastX
-	yoffset := lastY - ypos // Reversed since y-coordinates go from bottom to top
-	lastX = xpos
-	lastY = ypos
-
-	xoffset *= float64(mouseSensitivity)
-	yoffset *= float64(mouseSensitivity)
-
-	yaw += float32(xoffset)
-	pitch += float32(yoffset)
+	windowedX, windowedY = window.GetPos()
+	windowedWidth, windowedHeight = window.GetSize()
 
-	if pitch > 89.0 {
-		pitch = 89.0
-	}
-	if pitch < -89.0 {
-		pitch = -89.0
-	}
-
-	front := mgl32.Vec3{
-		float32(math.Cos(float64(mgl32.DegToRad(yaw))) * math.Cos(float64(mgl32.DegToRad(pitch)))),
-		float32(math.Sin(float64(mgl32.DegToRad(pitch)))),
-		float32(math.Sin(float64(mgl32.DegToRad(yaw))) * math.Cos(float64(mgl32.DegToRad(pitch)))),
-	}
-	cameraFront = front.Normalize()
+	monitor := glfw.GetPrimaryMonitor()
+	mode := monitor.GetVideoMode()
+	window.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
 }
 
-func keyCallback(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-	if action == glfw.Press {
-		switch key {
-		case glfw.KeyEscape:
-			captureMouse = !captureMouse
-			if captureMouse {
-				window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
-			} else {
-				window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
-			}
-		case glfw.KeyLeft:
-			mouseSensitivity -= 0.01
-			if mouseSensitivity < 0.01 {
-				mouseSensitivity = 0.01
-			}
-		case glfw.KeyRight:
-			mouseSensitivity += 0.01
-			if mouseSensitivity > 0.5 {
-				mouseSensitivity = 0.5
-			}
-		}
+// scrollCallback narrows the field of view when scrolling up and widens it
+// when scrolling down, or moves juliaC's z while the mouse drives juliaC.
+func scrollCallback(window *glfw.Window, xoff float64, yoff float64) {
+	if juliaMouse {
+		juliaC[2] = mgl32.Clamp(juliaC[2]+float32(yoff)*0.05, -juliaMouseRange, juliaMouseRange)
+		return
 	}
 
-	if action == glfw.Press || action == glfw.Repeat {
-		speed := float32(0.1)
-		switch key {
-		case glfw.KeyW:
-			camera = camera.Add(cameraFront.Mul(speed))
-		case glfw.KeyS:
-			camera = camera.Sub(cameraFront.Mul(speed))
-		case glfw.KeyA:
-			camera = camera.Sub(cameraFront.Cross(cameraUp).Normalize().Mul(speed))
-		case glfw.KeyD:
-			camera = camera.Add(cameraFront.Cross(cameraUp).Normalize().Mul(speed))
-		case glfw.KeyEqual:
-			scale += 0.1
-		case glfw.KeyMinus:
-			scale -= 0.1
-		case glfw.KeyQ:
-			debugZoom *= 0.9
-		case glfw.KeyE:
-			debugZoom *= 1.1
-		case glfw.KeyI:
-			debugOffset[1] += 0.1
-		case glfw.KeyK:
-			debugOffset[1] -= 0.1
-		case glfw.KeyJ:
-			debugOffset[0] -= 0.1
-		case glfw.KeyL:
-			debugOffset[0] += 0.1
-		case glfw.KeyU:
-			debugOffset[2] -= 0.1
-		case glfw.KeyO:
-			debugOffset[2] += 0.1
-		}
+	fov -= float32(yoff) * 2.0
+	if fov < 20.0 {
+		fov = 20.0
+	}
+	if fov > 120.0 {
+		fov = 120.0
 	}
+	updateProjection()
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"m-box_explore/render"
+)
+
+const (
+	minimapSize   = 200 // on-screen size in pixels
+	minimapMargin = 8
+	// The overview is ray-marched at a fraction of its on-screen size and
+	// with a reduced step budget to keep it cheap
+	minimapRenderSize = minimapSize / 2
+	minimapMaxSteps   = 64
+)
+
+var showMinimap bool
+
+// minimap draws a small overview of the whole fractal from a fixed camera
+// outside it in the bottom-right corner, with the main camera's position and
+// view frustum marked on it.
+type minimap struct {
+	copier  *render.Copy
+	fbo     uint32
+	texture uint32
+
+	lines *render.Program
+	vao   uint32
+	vbo   uint32
+}
+
+func newMinimap(copier *render.Copy) (*minimap, error) {
+	lines, err := render.NewProgram(boundsVertexShaderSource, boundsFragmentShaderSource)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &minimap{copier: copier, lines: lines}
+	m.fbo, m.texture = newColorTarget()
+	gl.BindTexture(gl.TEXTURE_2D, m.texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, minimapRenderSize, minimapRenderSize, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+
+	gl.GenVertexArrays(1, &m.vao)
+	gl.BindVertexArray(m.vao)
+	gl.GenBuffers(1, &m.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+
+	return m, nil
+}
+
+// overviewCamera looks at the origin from far enough out to take in the
+// current fractal's bounds.
+func overviewCamera() *camera.Camera {
+	c := camera.New(mgl32.Vec3{})
+	c.Yaw, c.Pitch = -60, -30
+	c.UpdateFront()
+	c.Orbit(mgl32.Vec3{}, boundsRadius()*2.5)
+	return c
+}
+
+func (m *minimap) draw(renderer *render.Renderer, cam *camera.Camera) {
+	overview := overviewCamera()
+
+	// A plain mono view at the minimap's size, cheaper than the main one
+	p := sceneParams(overview)
+	p.Width, p.Height = minimapRenderSize, minimapRenderSize
+	p.Stereo, p.Anaglyph, p.Crosshair = false, false, false
+	p.AASamples, p.MaxSteps, p.Reflectivity = 1, min(p.MaxSteps, minimapMaxSteps), 0
+	p.Zoom, p.Offset, p.Jitter = 1, mgl32.Vec3{}, mgl32.Vec2{}
+	overviewProjection := render.Perspective(p.FOV, p.Width, p.Height, p.Near, p.Far)
+
+	var target int32
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &target)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, m.fbo)
+	renderer.Draw(p)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(target))
+	size := int32(minimapSize * contentScale)
+	margin := int32(minimapMargin * contentScale)
+	gl.Viewport(fbWidth-size-margin, margin, size, size)
+	m.copier.Draw(m.texture)
+
+	vertices := cameraMarker(cam, boundsRadius())
+	mvp := overviewProjection.Mul4(overview.ViewMatrix())
+	m.lines.Use()
+	gl.UniformMatrix4fv(m.lines.Uniform("mvp"), 1, false, &mvp[0])
+	gl.Uniform3f(m.lines.Uniform("color"), 1, 0.2, 0.2)
+	gl.BindVertexArray(m.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STREAM_DRAW)
+	gl.DrawArrays(gl.LINES, 0, int32(len(vertices)/3))
+
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+}
+
+// cameraMarker returns line segments for a cross at the eye the scene is
+// drawn from and its view frustum, zoomed as the scene is, sized relative to
+// the overview's radius r.
+func cameraMarker(cam *camera.Camera, r float32) []float32 {
+	var vertices []float32
+	line := func(a, b mgl32.Vec3) {
+		vertices = append(vertices, a[0], a[1], a[2], b[0], b[1], b[2])
+	}
+
+	p := cam.Position.Add(debugOffset)
+	cross := r * 0.04
+	for axis := 0; axis < 3; axis++ {
+		var d mgl32.Vec3
+		d[axis] = cross
+		line(p.Sub(d), p.Add(d))
+	}
+
+	right := cam.Front.Cross(cam.Up).Normalize()
+	tanY := tanHalfViewFOV()
+	tanX := tanY * float32(fbWidth) / float32(fbHeight)
+	length := r * 0.15
+	var corners [4]mgl32.Vec3
+	for i, s := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		dir := cam.Front.Add(right.Mul(s[0] * tanX)).Add(cam.Up.Mul(s[1] * tanY))
+		corners[i] = p.Add(dir.Mul(length))
+		line(p, corners[i])
+	}
+	for i := range corners {
+		line(corners[i], corners[(i+1)%4])
+	}
+	return vertices
+}
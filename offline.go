@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"image"
+	"m-box_explore/camera"
+	"m-box_explore/render"
+)
+
+// Offline render settings, set with -render and -renderScale.
+var (
+	renderPath  string
+	renderScale = 1
+)
+
+// offscreen is a color framebuffer of a fixed size that frames are rendered
+// into and read back from, independent of the window.
+type offscreen struct {
+	fbo    uint32
+	rbo    uint32
+	width  int32
+	height int32
+}
+
+func newOffscreen(w, h int32) (*offscreen, error) {
+	o := &offscreen{width: w, height: h}
+
+	gl.GenFramebuffers(1, &o.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, o.fbo)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	gl.GenRenderbuffers(1, &o.rbo)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, o.rbo)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.RGBA8, w, h)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, o.rbo)
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		o.delete()
+		return nil, fmt.Errorf("off-screen framebuffer %dx%d incomplete: 0x%x", w, h, status)
+	}
+	return o, nil
+}
+
+// render draws the scene with p, resized to the target, and reads the
+// result back.
+func (o *offscreen) render(renderer *render.Renderer, p render.Params) *image.RGBA {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, o.fbo)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	defer gl.Viewport(0, 0, fbWidth, fbHeight)
+
+	p.Width, p.Height = o.width, o.height
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	renderer.Draw(p)
+
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	return render.ReadPixels(int(o.width), int(o.height))
+}
+
+func (o *offscreen) delete() {
+	gl.DeleteRenderbuffers(1, &o.rbo)
+	gl.DeleteFramebuffers(1, &o.fbo)
+}
+
+// renderOffline draws a single frame into an off-screen framebuffer at
+// renderScale times the window resolution and writes it to path as a PNG.
+func renderOffline(path string, renderer *render.Renderer, cam *camera.Camera) error {
+	w := fbWidth * int32(renderScale)
+	h := fbHeight * int32(renderScale)
+
+	target, err := newOffscreen(w, h)
+	if err != nil {
+		return err
+	}
+	defer target.delete()
+
+	img := target.render(renderer, sceneParams(cam))
+	if err := render.WritePNG(path, img); err != nil {
+		return err
+	}
+
+	fmt.Printf("rendered %dx%d to %s\n", w, h, path)
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// paletteTextureUnit is kept clear of unit 0, which the HUD rebinds.
+const paletteTextureUnit = 1
+
+var (
+	// palettePath is the gradient file given with -palette, if any
+	palettePath string
+
+	// paletteTexture is the texture bound to paletteTextureUnit
+	paletteTexture uint32
+)
+
+// defaultPalette steps around the hue wheel at the saturation the shader
+// originally used; interpolating linearly between these stops matches HSV.
+var defaultPalette = []mgl32.Vec3{
+	{1.0, 0.2, 0.2},
+	{1.0, 1.0, 0.2},
+	{0.2, 1.0, 0.2},
+	{0.2, 1.0, 1.0},
+	{0.2, 0.2, 1.0},
+	{1.0, 0.2, 1.0},
+}
+
+// loadPalette reads RGB stops, one per line, separated by commas or
+// whitespace. Blank lines and lines starting with '#' are skipped. Values
+// are taken as 0-1 unless any exceeds 1, in which case all are read as 0-255.
+func loadPalette(path string) ([]mgl32.Vec3, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsePalette(path, f)
+}
+
+// parsePalette reads stops in loadPalette's format from r, naming the source
+// in errors.
+func parsePalette(path string, r io.Reader) ([]mgl32.Vec3, error) {
+	var stops []mgl32.Vec3
+	byteRange := false
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t'
+		})
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected 3 components, got %d", path, lineNum, len(fields))
+		}
+
+		var stop mgl32.Vec3
+		for i, field := range fields {
+			v, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+			}
+			if v > 1 {
+				byteRange = true
+			}
+			stop[i] = float32(v)
+		}
+		stops = append(stops, stop)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(stops) == 0 {
+		return nil, fmt.Errorf("%s: no color stops", path)
+	}
+
+	if byteRange {
+		for i := range stops {
+			stops[i] = stops[i].Mul(1.0 / 255.0)
+		}
+	}
+	return stops, nil
+}
+
+// uploadPalette stores the stops in a wrapping 1D texture and binds it to
+// paletteTextureUnit.
+func uploadPalette(stops []mgl32.Vec3) uint32 {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.ActiveTexture(gl.TEXTURE0 + paletteTextureUnit)
+	gl.BindTexture(gl.TEXTURE_1D, texture)
+	gl.TexParameteri(gl.TEXTURE_1D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_1D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_1D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexImage1D(gl.TEXTURE_1D, 0, gl.RGB32F, int32(len(stops)), 0, gl.RGB, gl.FLOAT, gl.Ptr(stops))
+	gl.ActiveTexture(gl.TEXTURE0)
+	return texture
+}
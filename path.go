@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"log"
+	"m-box_explore/camera"
+	"math"
+	"os"
+)
+
+const pathFile = "path.json"
+
+// Keyframe is one recorded camera sample, Time seconds into the recording.
+type Keyframe struct {
+	Time     float64    `json:"time"`
+	Position mgl32.Vec3 `json:"position"`
+	Yaw      float32    `json:"yaw"`
+	Pitch    float32    `json:"pitch"`
+	Roll     float32    `json:"roll"`
+}
+
+var (
+	recording    bool
+	playing      bool
+	recordedPath []Keyframe
+	pathStart    float64
+)
+
+func savePath(filename string, keyframes []Keyframe) error {
+	data, err := json.MarshalIndent(keyframes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+func loadPath(filename string) ([]Keyframe, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyframes []Keyframe
+	if err := json.Unmarshal(data, &keyframes); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	return keyframes, nil
+}
+
+// toggleRecording starts a new recording, or stops the current one and
+// saves it to pathFile.
+func toggleRecording() {
+	if playing {
+		return
+	}
+
+	recording = !recording
+	if recording {
+		recordedPath = nil
+		pathStart = glfw.GetTime()
+		fmt.Println("recording camera path")
+		return
+	}
+
+	if err := savePath(pathFile, recordedPath); err != nil {
+		log.Println("failed to save camera path:", err)
+		return
+	}
+	fmt.Printf("saved %d keyframes to %s\n", len(recordedPath), pathFile)
+}
+
+// togglePlayback replays the path in pathFile from the start.
+func togglePlayback() {
+	if recording {
+		return
+	}
+	if playing {
+		playing = false
+		return
+	}
+
+	keyframes, err := loadPath(pathFile)
+	if err != nil {
+		log.Println("failed to load camera path:", err)
+		return
+	}
+	if len(keyframes) == 0 {
+		fmt.Println("camera path is empty")
+		return
+	}
+
+	recordedPath = keyframes
+	pathStart = glfw.GetTime()
+	playing = true
+}
+
+// updatePath appends the camera to the recording, or moves it along the
+// path being played back.
+func updatePath(cam *camera.Camera) {
+	t := glfw.GetTime() - pathStart
+
+	if recording {
+		recordedPath = append(recordedPath, Keyframe{
+			Time:     t,
+			Position: cam.Position,
+			Yaw:      cam.Yaw,
+			Pitch:    cam.Pitch,
+			Roll:     cam.Roll,
+		})
+	}
+
+	if playing {
+		k, ok := samplePath(recordedPath, t)
+		if !ok {
+			playing = false
+			return
+		}
+		cam.Position = k.Position
+		cam.Velocity = mgl32.Vec3{}
+		cam.Yaw = k.Yaw
+		cam.Pitch = k.Pitch
+		cam.Roll = k.Roll
+		cam.UpdateFront()
+	}
+}
+
+// samplePath linearly interpolates the keyframes at time t, holding the
+// first pose until the path starts. It reports false once t is past the end
+// of the path.
+func samplePath(keyframes []Keyframe, t float64) (Keyframe, bool) {
+	if len(keyframes) == 0 || t > keyframes[len(keyframes)-1].Time {
+		return Keyframe{}, false
+	}
+	if t <= keyframes[0].Time {
+		return keyframes[0], true
+	}
+
+	for i := 1; i < len(keyframes); i++ {
+		a, b := keyframes[i-1], keyframes[i]
+		if t > b.Time {
+			continue
+		}
+
+		f := float32(0)
+		if b.Time > a.Time {
+			f = float32((t - a.Time) / (b.Time - a.Time))
+		}
+		return Keyframe{
+			Time:     t,
+			Position: a.Position.Add(b.Position.Sub(a.Position).Mul(f)),
+			Yaw:      lerpAngle(a.Yaw, b.Yaw, f),
+			Pitch:    a.Pitch + (b.Pitch-a.Pitch)*f,
+			Roll:     a.Roll + (b.Roll-a.Roll)*f,
+		}, true
+	}
+	return keyframes[0], true
+}
+
+// lerpAngle interpolates from a to b degrees the short way round, since the
+// camera's yaw wraps from 180 to -180.
+func lerpAngle(a, b, f float32) float32 {
+	d := float32(math.Mod(float64(b-a)+180, 360))
+	if d < 0 {
+		d += 360
+	}
+	return a + (d-180)*f
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/go-gl/mathgl/mgl64"
+	"m-box_explore/camera"
+)
+
+// The last surface point clicked on, shown in the HUD once picked is set.
+var (
+	picked      bool
+	pickedPoint mgl32.Vec3
+)
+
+// pick marches a ray through the window position x, y (fractions of the
+// window from the bottom-left corner) and, if it hits the fractal, focuses
+// the depth of field on the hit. In orbit mode the hit also becomes the
+// orbit target, with the camera turned to face it.
+func pick(cam *camera.Camera, x, y float64) {
+	origin := vec64(cam.Position.Add(debugOffset))
+	dir := pickRay(cam, x, y)
+	t, ok := marchDistance(origin, dir)
+	if !ok {
+		fmt.Println("pick: nothing under the cursor")
+		return
+	}
+	hit := vec32(origin.Add(dir.Mul(t)))
+
+	picked = true
+	pickedPoint = hit
+	focusDistance = float32(t)
+	if orbitMode {
+		// The hit is seen from the offset eye while Orbit places the camera,
+		// so the target is the hit moved back by debugOffset. The eye
+		// already looks along dir to it, so the view doesn't move.
+		orbitTarget = hit.Sub(debugOffset)
+		orbitRadius = float32(t)
+		cam.Face(vec32(dir))
+		cam.Orbit(orbitTarget, orbitRadius)
+	}
+	fmt.Printf("picked %.4f, %.4f, %.4f at distance %.4f\n", hit[0], hit[1], hit[2], t)
+}
+
+// pickRay is the direction of the ray through x, y, built the same way as
+// in the shader's render.
+func pickRay(cam *camera.Camera, x, y float64) mgl64.Vec3 {
+	u := (x*2 - 1) * float64(fbWidth) / float64(fbHeight) / float64(debugZoom)
+	v := (y*2 - 1) / float64(debugZoom)
+	tanHalfFov := 1 / float64(projection.At(1, 1))
+
+	front := vec64(cam.Front)
+	right := front.Cross(vec64(cam.Up)).Normalize()
+	up := right.Cross(front)
+	return front.Add(right.Mul(u * tanHalfFov)).Add(up.Mul(v * tanHalfFov)).Normalize()
+}
+
+// marchDistance sphere-traces from origin along dir with the shader's step
+// budget, hit threshold and level of detail, origin being the eye, returning
+// the distance to the surface if it is reached.
+func marchDistance(origin, dir mgl64.Vec3) (float64, bool) {
+	t := 0.0
+	for i := 0; i < int(maxSteps); i++ {
+		d := sceneDistance(origin.Add(dir.Mul(t)), origin)
+		if d < float64(surfaceEpsilon)*(1+t*float64(epsilonFactor)) {
+			return t, true
+		}
+		t += d
+		if t > float64(maxDistance) {
+			break
+		}
+	}
+	return 0, false
+}
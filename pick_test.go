@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"testing"
+)
+
+func TestPickOrbitWithOffset(t *testing.T) {
+	oldSphere, oldOrbit, oldOffset := testSphere, orbitMode, debugOffset
+	oldTarget, oldRadius, oldPicked, oldFocus := orbitTarget, orbitRadius, picked, focusDistance
+	oldFOV, oldW, oldH := fov, fbWidth, fbHeight
+	defer func() {
+		testSphere, orbitMode, debugOffset = oldSphere, oldOrbit, oldOffset
+		orbitTarget, orbitRadius, picked, focusDistance = oldTarget, oldRadius, oldPicked, oldFocus
+		fov, fbWidth, fbHeight = oldFOV, oldW, oldH
+		updateProjection()
+	}()
+
+	// The test sphere sits at 0,0,-3; from an eye offset sideways and up, the
+	// ray through the middle of the window still hits it
+	testSphere, orbitMode = true, true
+	debugOffset = mgl32.Vec3{0.3, 0.2, 0}
+	fov, fbWidth, fbHeight = 60, 64, 64
+	updateProjection()
+
+	cam := camera.New(mgl32.Vec3{0, 0, 1})
+	cam.Yaw, cam.Pitch = -90, -3
+	cam.UpdateFront()
+	position, front := cam.Position, cam.Front
+
+	pick(cam, 0.5, 0.5)
+	if !picked {
+		t.Fatal("pick missed the sphere")
+	}
+	if !approxVec3(cam.Position, position) || !approxVec3(cam.Front, front) {
+		t.Errorf("view moved to %v facing %v, want %v facing %v", cam.Position, cam.Front, position, front)
+	}
+	if got := orbitTarget.Add(debugOffset); !approxVec3(got, pickedPoint) {
+		t.Errorf("orbit target + offset = %v, want the picked point %v", got, pickedPoint)
+	}
+
+	// Orbiting the new target from here must leave the eye where it is
+	cam.Orbit(orbitTarget, orbitRadius)
+	if !approxVec3(cam.Position, position) {
+		t.Errorf("Orbit moved the camera to %v, want %v", cam.Position, position)
+	}
+}
+
+// approxVec3 compares component by component, as the camera tests do.
+func approxVec3(a, b mgl32.Vec3) bool {
+	const epsilon = 1e-3
+	for i := range a {
+		if mgl32.Abs(a[i]-b[i]) > epsilon {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"log"
+	"m-box_explore/camera"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// presetsDir holds the shipped presets, one JSON file per preset, along
+// with any palette files they name.
+const presetsDir = "presets"
+
+// embeddedPresets are the shipped presets built into the binary, so they're
+// available whatever directory the explorer is started from.
+//
+//go:embed presets/*
+var embeddedPresets embed.FS
+
+var (
+	// presetName is the preset given with -preset, applied at startup
+	presetName string
+
+	// presetIndex is the position of the last preset loaded in presetNames,
+	// for cycling through them
+	presetIndex = -1
+)
+
+// Preset is a curated view with the fractal parameters and colors that make
+// it. Fields left out of the file keep their current values, or for the
+// camera, the starting view.
+type Preset struct {
+	Position mgl32.Vec3 `json:"position"`
+	Yaw      float32    `json:"yaw"`
+	Pitch    float32    `json:"pitch"`
+
+	FractalType   int32      `json:"fractalType"`
+	Scale         float32    `json:"scale"`
+	MaxIterations int32      `json:"maxIterations"`
+	FoldingLimit  float32    `json:"foldingLimit"`
+	FoldRotation  mgl32.Vec3 `json:"foldRotation"`
+	JuliaMode     bool       `json:"juliaMode"`
+	JuliaC        mgl32.Vec3 `json:"juliaC"`
+
+	ColorMode int32   `json:"colorMode"`
+	HueShift  float32 `json:"hueShift"`
+	// Palette is a palette file in presetsDir, or empty for the one the
+	// explorer started with
+	Palette string `json:"palette"`
+}
+
+// presetNames lists the presets in file name order: the embedded ones along
+// with any added to presetsDir on disk.
+func presetNames() ([]string, error) {
+	embedded, err := embeddedPresets.ReadDir(presetsDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(presetsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, e := range append(embedded, entries...) {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".json" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readPresetFile prefers the copy of a file in presetsDir on disk so presets
+// can be edited and added without rebuilding, and falls back to the embedded
+// version.
+func readPresetFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(presetsDir, name))
+	if err == nil {
+		return data, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return embeddedPresets.ReadFile(presetsDir + "/" + name)
+}
+
+// loadPreset reads presetsDir/name.json over the current state.
+func loadPreset(name string) (Preset, error) {
+	p := Preset{
+		Position:      startPos,
+		Yaw:           startYaw,
+		Pitch:         startPitch,
+		FractalType:   fractalType,
+		Scale:         scale,
+		MaxIterations: maxIterations,
+		FoldingLimit:  foldingLimit,
+		FoldRotation:  foldRotation,
+		JuliaMode:     juliaMode,
+		JuliaC:        juliaC,
+		ColorMode:     colorMode,
+		HueShift:      hueShift,
+	}
+	path := filepath.Join(presetsDir, name+".json")
+	data, err := readPresetFile(name + ".json")
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if p.FractalType < 0 || p.FractalType >= numFractalTypes {
+		return p, fmt.Errorf("%s: unknown fractal type %d", path, p.FractalType)
+	}
+	return p, nil
+}
+
+// apply switches to the preset's parameters and palette and moves cam to its
+// view. The palette is uploaded, so this needs a current GL context.
+func (p Preset) apply(cam *camera.Camera) error {
+	stops := defaultPalette
+	var err error
+	switch {
+	case p.Palette != "":
+		var data []byte
+		if data, err = readPresetFile(p.Palette); err == nil {
+			stops, err = parsePalette(filepath.Join(presetsDir, p.Palette), bytes.NewReader(data))
+		}
+	case palettePath != "":
+		stops, err = loadPalette(palettePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load palette: %v", err)
+	}
+	gl.DeleteTextures(1, &paletteTexture)
+	paletteTexture = uploadPalette(stops)
+
+	fractalType = p.FractalType
+	scale = mgl32.Clamp(p.Scale, minScale, maxScale)
+	baseScale = scale
+	maxIterations = min(max(p.MaxIterations, 1), 1000)
+	foldingLimit = p.FoldingLimit
+	foldRotation = p.FoldRotation
+	juliaMode = p.JuliaMode
+	juliaC = p.JuliaC
+	colorMode = wrapIndex(p.ColorMode, numColorModes)
+	hueShift = p.HueShift
+
+	cam.Position = p.Position
+	cam.Velocity = mgl32.Vec3{}
+	cam.Yaw = p.Yaw
+	cam.Pitch = mgl32.Clamp(p.Pitch, -89, 89)
+	cam.Roll = 0
+	cam.UpdateFront()
+	if orbitMode {
+		orbitRadius = cam.Position.Sub(orbitTarget).Len()
+	}
+	return nil
+}
+
+// nextPreset loads the preset after the last one loaded, wrapping around.
+func nextPreset(cam *camera.Camera) {
+	names, err := presetNames()
+	if err != nil {
+		log.Println("failed to list presets:", err)
+		return
+	}
+	if len(names) == 0 {
+		fmt.Println("no presets in", presetsDir)
+		return
+	}
+
+	presetIndex = (presetIndex + 1) % len(names)
+	name := names[presetIndex]
+	p, err := loadPreset(name)
+	if err == nil {
+		err = p.apply(cam)
+	}
+	if err != nil {
+		log.Println("failed to load preset:", err)
+		return
+	}
+	fmt.Println("preset:", name)
+}
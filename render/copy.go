@@ -0,0 +1,46 @@
+package render
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+const copyFragmentShader = `
+	#version 330 core
+	in vec2 uv;
+	out vec4 FragColor;
+	uniform sampler2D image;
+	void main() {
+		FragColor = texture(image, uv);
+	}
+` + "\x00"
+
+// Copy draws a texture over the whole viewport. Unlike BlitFramebuffer it
+// can draw into a multisampled framebuffer, and it filters with whatever
+// the texture's own filter is when the sizes differ.
+type Copy struct {
+	program *Program
+	quad    *Quad
+}
+
+// NewCopy builds the copy program, drawing with quad, which it doesn't own.
+func NewCopy(quad *Quad) (*Copy, error) {
+	program, err := NewProgram(QuadVertexShader, copyFragmentShader)
+	if err != nil {
+		return nil, err
+	}
+	return &Copy{program: program, quad: quad}, nil
+}
+
+// Draw copies texture, bound on texture unit 0, into the current viewport.
+func (c *Copy) Draw(texture uint32) {
+	c.program.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.Uniform1i(c.program.Uniform("image"), 0)
+	c.quad.Draw()
+}
+
+// Delete frees the program, leaving the shared quad alone.
+func (c *Copy) Delete() {
+	c.program.Delete()
+}
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// QuadVertexShader passes Quad's corners through and gives the fragment
+// shader uv, running 0-1 across the screen.
+const QuadVertexShader = `
+	#version 330 core
+	layout (location = 0) in vec3 aPos;
+	out vec2 uv;
+	void main() {
+		uv = aPos.xy * 0.5 + 0.5;
+		gl_Position = vec4(aPos, 1.0);
+	}
+` + "\x00"
+
+// Quad is a full-screen triangle strip with positions at attribute 0, for
+// passes that do all their work in the fragment shader.
+type Quad struct {
+	vao uint32
+	vbo uint32
+}
+
+// NewQuad uploads the quad's vertices. It needs a current GL context.
+func NewQuad() *Quad {
+	vertices := []float32{
+		-1.0, -1.0, 0.0,
+		1.0, -1.0, 0.0,
+		-1.0, 1.0, 0.0,
+		1.0, 1.0, 0.0,
+	}
+
+	q := &Quad{}
+	gl.GenVertexArrays(1, &q.vao)
+	gl.BindVertexArray(q.vao)
+
+	gl.GenBuffers(1, &q.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, q.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+
+	return q
+}
+
+// Draw draws the quad with whatever program is in use.
+func (q *Quad) Draw() {
+	gl.BindVertexArray(q.vao)
+	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+}
+
+// Delete frees the quad's vertex array and buffer.
+func (q *Quad) Delete() {
+	gl.DeleteVertexArrays(1, &q.vao)
+	gl.DeleteBuffers(1, &q.vbo)
+}
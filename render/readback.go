@@ -0,0 +1,37 @@
+package render
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"image"
+	"image/png"
+	"os"
+)
+
+// ReadPixels copies the current read buffer into an image, flipping it
+// since OpenGL's origin is bottom-left and image's is top-left.
+func ReadPixels(width, height int) *image.RGBA {
+	pixels := make([]byte, width*height*4)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(pixels))
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	stride := width * 4
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*stride:(y+1)*stride], pixels[(height-1-y)*stride:(height-y)*stride])
+	}
+	return img
+}
+
+// WritePNG encodes img as a PNG file at path, replacing any file there.
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,349 @@
+package render
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Params is everything one draw of the fractal depends on. The caller fills
+// in a fresh copy per draw, so a secondary view such as an overview map can
+// change a few fields without touching the main view's settings.
+type Params struct {
+	// Camera and target. Width and Height are the size of the framebuffer
+	// being drawn into, in pixels. Zoom narrows the rays around the view
+	// center and Offset moves their origins without moving the camera.
+	Position, Front, Up mgl32.Vec3
+	Width, Height       int32
+	FOV                 float32 // vertical, in degrees
+	Near, Far           float32 // Far is also the ray-march cutoff
+	Zoom                float32
+	Offset              mgl32.Vec3
+	Jitter              mgl32.Vec2 // sub-pixel offset of this draw's samples
+	AASamples           int32      // rays per pixel along each axis
+
+	// Side-by-side stereo, or red-cyan anaglyph with the left eye in red
+	// unless AnaglyphSwap is set
+	Stereo, Anaglyph, AnaglyphSwap bool
+	EyeSeparation                  float32
+
+	// The fractal. Time is the animation clock and FoldRotation is in
+	// degrees about x, y and z.
+	Time                 float32
+	FractalType          int32
+	Scale                float32
+	MaxIterations        int32
+	LODFalloff           float32
+	FoldingLimit         float32
+	MinRadius            float32
+	FixedRadius          float32
+	EscapeRadius         float32
+	MengerIterations     int32
+	SierpinskiIterations int32
+	FoldRotation         mgl32.Vec3
+	JuliaMode            bool
+	JuliaC               mgl32.Vec3
+	TestSphere           bool
+
+	// Ray-march budget
+	MaxSteps       int32
+	SurfaceEpsilon float32
+	EpsilonFactor  float32
+
+	// Lighting and atmosphere
+	LightPos        mgl32.Vec3
+	LightDir        mgl32.Vec3
+	ShadowSoftness  float32
+	AOStrength      float32
+	MaxReflections  int32
+	SoftShadowSteps int32
+	KeyLight        bool
+	FillLight       bool
+	FillLightDir    mgl32.Vec3
+	FillLightColor  mgl32.Vec3
+	Ambient         bool
+	AmbientColor    mgl32.Vec3
+	FogColor        mgl32.Vec3
+	FogDensity      float32
+	Reflectivity    float32
+	SkyTop          mgl32.Vec3
+	SkyBottom       mgl32.Vec3
+	SunIntensity    float32
+	UseEnvironment  bool
+	EnvironmentUnit int32 // texture unit holding the environment cubemap
+
+	// Coloring and output
+	ColorMode      int32
+	ColorCurve     int32
+	ColorGamma     float32
+	ColorScale     float32
+	HueShift       float32
+	PaletteUnit    int32 // texture unit holding the 1D palette
+	ShowDepth      bool
+	StepHeatmap    bool
+	Dither         float32
+	Gamma          float32
+	Transparent    bool
+	ContentScale   float32
+	Crosshair      bool
+	CrosshairColor mgl32.Vec3
+}
+
+// Perspective returns the projection for a vertical field of view of fov
+// degrees onto a w x h target.
+func Perspective(fov float32, w, h int32, near, far float32) mgl32.Mat4 {
+	return mgl32.Perspective(mgl32.DegToRad(fov), float32(w)/float32(h), near, far)
+}
+
+// sceneUniforms are the fractal program's uniform locations, looked up once
+// per build of the program rather than by name on every draw.
+type sceneUniforms struct {
+	time, cameraPos, cameraFront, cameraUp, scale, maxIterations,
+	lodFalloff, fractalType, foldingLimit, minRadius, fixedRadius,
+	escapeRadius, mengerIterations, sierpinskiIterations, rotX, rotY,
+	rotZ, juliaMode, juliaC, lightPos, shadowSoftness, lightDir,
+	aoStrength, maxReflections, softShadowSteps, keyLightEnabled,
+	fillLightEnabled, fillLightDir, fillLightColor, ambientEnabled,
+	ambientColor, aaSamples, jitter, maxSteps, surfaceEpsilon,
+	epsilonFactor, maxDistance, fogColor, fogDensity, testSphere,
+	debugZoom, debugOffset, reflectivity, skyTop, skyBottom,
+	sunIntensity, colorMode, colorCurve, colorGamma, colorScale,
+	hueShift, palette, useEnvironment, environment, showDepth,
+	debugStepHeatmap, ditherStrength, gamma, transparentBackground,
+	contentScale, showCrosshair, crosshairColor, eyeSeparation, eye,
+	viewportOrigin, resolution, projection int32
+}
+
+func (u *sceneUniforms) lookup(p *Program) {
+	u.time = p.Uniform("time")
+	u.cameraPos = p.Uniform("cameraPos")
+	u.cameraFront = p.Uniform("cameraFront")
+	u.cameraUp = p.Uniform("cameraUp")
+	u.scale = p.Uniform("scale")
+	u.maxIterations = p.Uniform("maxIterations")
+	u.lodFalloff = p.Uniform("lodFalloff")
+	u.fractalType = p.Uniform("fractalType")
+	u.foldingLimit = p.Uniform("foldingLimit")
+	u.minRadius = p.Uniform("minRadius")
+	u.fixedRadius = p.Uniform("fixedRadius")
+	u.escapeRadius = p.Uniform("escapeRadius")
+	u.mengerIterations = p.Uniform("mengerIterations")
+	u.sierpinskiIterations = p.Uniform("sierpinskiIterations")
+	u.rotX = p.Uniform("rotX")
+	u.rotY = p.Uniform("rotY")
+	u.rotZ = p.Uniform("rotZ")
+	u.juliaMode = p.Uniform("juliaMode")
+	u.juliaC = p.Uniform("juliaC")
+	u.lightPos = p.Uniform("lightPos")
+	u.shadowSoftness = p.Uniform("shadowSoftness")
+	u.lightDir = p.Uniform("lightDir")
+	u.aoStrength = p.Uniform("aoStrength")
+	u.maxReflections = p.Uniform("maxReflections")
+	u.softShadowSteps = p.Uniform("softShadowSteps")
+	u.keyLightEnabled = p.Uniform("keyLightEnabled")
+	u.fillLightEnabled = p.Uniform("fillLightEnabled")
+	u.fillLightDir = p.Uniform("fillLightDir")
+	u.fillLightColor = p.Uniform("fillLightColor")
+	u.ambientEnabled = p.Uniform("ambientEnabled")
+	u.ambientColor = p.Uniform("ambientColor")
+	u.aaSamples = p.Uniform("aaSamples")
+	u.jitter = p.Uniform("jitter")
+	u.maxSteps = p.Uniform("maxSteps")
+	u.surfaceEpsilon = p.Uniform("surfaceEpsilon")
+	u.epsilonFactor = p.Uniform("epsilonFactor")
+	u.maxDistance = p.Uniform("maxDistance")
+	u.fogColor = p.Uniform("fogColor")
+	u.fogDensity = p.Uniform("fogDensity")
+	u.testSphere = p.Uniform("testSphere")
+	u.debugZoom = p.Uniform("debugZoom")
+	u.debugOffset = p.Uniform("debugOffset")
+	u.reflectivity = p.Uniform("reflectivity")
+	u.skyTop = p.Uniform("skyTop")
+	u.skyBottom = p.Uniform("skyBottom")
+	u.sunIntensity = p.Uniform("sunIntensity")
+	u.colorMode = p.Uniform("colorMode")
+	u.colorCurve = p.Uniform("colorCurve")
+	u.colorGamma = p.Uniform("colorGamma")
+	u.colorScale = p.Uniform("colorScale")
+	u.hueShift = p.Uniform("hueShift")
+	u.palette = p.Uniform("palette")
+	u.useEnvironment = p.Uniform("useEnvironment")
+	u.environment = p.Uniform("environment")
+	u.showDepth = p.Uniform("showDepth")
+	u.debugStepHeatmap = p.Uniform("debugStepHeatmap")
+	u.ditherStrength = p.Uniform("ditherStrength")
+	u.gamma = p.Uniform("gamma")
+	u.transparentBackground = p.Uniform("transparentBackground")
+	u.contentScale = p.Uniform("contentScale")
+	u.showCrosshair = p.Uniform("showCrosshair")
+	u.crosshairColor = p.Uniform("crosshairColor")
+	u.eyeSeparation = p.Uniform("eyeSeparation")
+	u.eye = p.Uniform("eye")
+	u.viewportOrigin = p.Uniform("viewportOrigin")
+	u.resolution = p.Uniform("resolution")
+	u.projection = p.Uniform("projection")
+}
+
+// Renderer ray-marches the fractal: it owns the fractal program and the
+// full-screen quad it is drawn on, which the post-processing passes share.
+type Renderer struct {
+	program  *Program
+	uniforms sceneUniforms
+	quad     *Quad
+}
+
+// NewRenderer builds the fractal program from the given shader sources and
+// sets up the depth state it relies on.
+func NewRenderer(vertexSource, fragmentSource string) (*Renderer, error) {
+	program, err := NewProgram(vertexSource, fragmentSource)
+	if err != nil {
+		return nil, err
+	}
+
+	// The shader writes linear depth itself, and depth is only written
+	// with the test enabled
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthFunc(gl.ALWAYS)
+
+	r := &Renderer{program: program, quad: NewQuad()}
+	r.uniforms.lookup(program)
+	return r, nil
+}
+
+// Quad returns the full-screen quad, for passes that draw over the scene.
+func (r *Renderer) Quad() *Quad {
+	return r.quad
+}
+
+// Reload rebuilds the fractal program from new sources, keeping the current
+// one if they don't compile.
+func (r *Renderer) Reload(vertexSource, fragmentSource string) error {
+	if err := r.program.Reload(vertexSource, fragmentSource); err != nil {
+		return err
+	}
+	r.uniforms.lookup(r.program)
+	return nil
+}
+
+// Delete frees the fractal program and the quad.
+func (r *Renderer) Delete() {
+	r.program.Delete()
+	r.quad.Delete()
+}
+
+// Draw uploads p and ray-marches the fractal into whatever framebuffer is
+// bound, which must be p.Width x p.Height. The viewport is left covering
+// the whole target.
+func (r *Renderer) Draw(p Params) {
+	u := &r.uniforms
+	r.program.Use()
+
+	gl.Uniform1f(u.time, p.Time)
+	gl.Uniform3fv(u.cameraPos, 1, &p.Position[0])
+	gl.Uniform3fv(u.cameraFront, 1, &p.Front[0])
+	gl.Uniform3fv(u.cameraUp, 1, &p.Up[0])
+	gl.Uniform1f(u.scale, p.Scale)
+	gl.Uniform1i(u.maxIterations, p.MaxIterations)
+	gl.Uniform1f(u.lodFalloff, p.LODFalloff)
+	gl.Uniform1i(u.fractalType, p.FractalType)
+	gl.Uniform1f(u.foldingLimit, p.FoldingLimit)
+	gl.Uniform1f(u.minRadius, p.MinRadius)
+	gl.Uniform1f(u.fixedRadius, p.FixedRadius)
+	gl.Uniform1f(u.escapeRadius, p.EscapeRadius)
+	gl.Uniform1i(u.mengerIterations, p.MengerIterations)
+	gl.Uniform1i(u.sierpinskiIterations, p.SierpinskiIterations)
+	gl.Uniform1f(u.rotX, mgl32.DegToRad(p.FoldRotation[0]))
+	gl.Uniform1f(u.rotY, mgl32.DegToRad(p.FoldRotation[1]))
+	gl.Uniform1f(u.rotZ, mgl32.DegToRad(p.FoldRotation[2]))
+	gl.Uniform1i(u.juliaMode, boolToInt(p.JuliaMode))
+	gl.Uniform3fv(u.juliaC, 1, &p.JuliaC[0])
+	gl.Uniform3fv(u.lightPos, 1, &p.LightPos[0])
+	gl.Uniform1f(u.shadowSoftness, p.ShadowSoftness)
+	gl.Uniform3fv(u.lightDir, 1, &p.LightDir[0])
+	gl.Uniform1f(u.aoStrength, p.AOStrength)
+	gl.Uniform1i(u.maxReflections, p.MaxReflections)
+	gl.Uniform1i(u.softShadowSteps, p.SoftShadowSteps)
+	gl.Uniform1i(u.keyLightEnabled, boolToInt(p.KeyLight))
+	gl.Uniform1i(u.fillLightEnabled, boolToInt(p.FillLight))
+	gl.Uniform3fv(u.fillLightDir, 1, &p.FillLightDir[0])
+	gl.Uniform3fv(u.fillLightColor, 1, &p.FillLightColor[0])
+	gl.Uniform1i(u.ambientEnabled, boolToInt(p.Ambient))
+	gl.Uniform3fv(u.ambientColor, 1, &p.AmbientColor[0])
+	gl.Uniform1i(u.aaSamples, p.AASamples)
+	gl.Uniform2f(u.jitter, p.Jitter[0], p.Jitter[1])
+	gl.Uniform1i(u.maxSteps, p.MaxSteps)
+	gl.Uniform1f(u.surfaceEpsilon, p.SurfaceEpsilon)
+	gl.Uniform1f(u.epsilonFactor, p.EpsilonFactor)
+	gl.Uniform1f(u.maxDistance, p.Far)
+	gl.Uniform3fv(u.fogColor, 1, &p.FogColor[0])
+	gl.Uniform1f(u.fogDensity, p.FogDensity)
+	gl.Uniform1i(u.testSphere, boolToInt(p.TestSphere))
+	gl.Uniform1f(u.debugZoom, p.Zoom)
+	gl.Uniform3fv(u.debugOffset, 1, &p.Offset[0])
+	gl.Uniform1f(u.reflectivity, p.Reflectivity)
+	gl.Uniform3fv(u.skyTop, 1, &p.SkyTop[0])
+	gl.Uniform3fv(u.skyBottom, 1, &p.SkyBottom[0])
+	gl.Uniform1f(u.sunIntensity, p.SunIntensity)
+	gl.Uniform1i(u.colorMode, p.ColorMode)
+	gl.Uniform1i(u.colorCurve, p.ColorCurve)
+	gl.Uniform1f(u.colorGamma, p.ColorGamma)
+	gl.Uniform1f(u.colorScale, p.ColorScale)
+	gl.Uniform1f(u.hueShift, p.HueShift)
+	gl.Uniform1i(u.palette, p.PaletteUnit)
+	gl.Uniform1i(u.useEnvironment, boolToInt(p.UseEnvironment))
+	gl.Uniform1i(u.environment, p.EnvironmentUnit)
+	gl.Uniform1i(u.showDepth, boolToInt(p.ShowDepth))
+	gl.Uniform1i(u.debugStepHeatmap, boolToInt(p.StepHeatmap))
+	gl.Uniform1f(u.ditherStrength, p.Dither)
+	gl.Uniform1f(u.gamma, p.Gamma)
+	gl.Uniform1i(u.transparentBackground, boolToInt(p.Transparent))
+	gl.Uniform1f(u.contentScale, p.ContentScale)
+	gl.Uniform1i(u.showCrosshair, boolToInt(p.Crosshair))
+	gl.Uniform3fv(u.crosshairColor, 1, &p.CrosshairColor[0])
+	gl.Uniform1f(u.eyeSeparation, p.EyeSeparation)
+
+	if p.Anaglyph {
+		left, right := float32(-1), float32(1)
+		if p.AnaglyphSwap {
+			left, right = right, left
+		}
+		projection := Perspective(p.FOV, p.Width, p.Height, p.Near, p.Far)
+		gl.ColorMask(true, false, false, true)
+		r.drawView(p, left, 0, p.Width, projection)
+		gl.ColorMask(false, true, true, true)
+		r.drawView(p, right, 0, p.Width, projection)
+		gl.ColorMask(true, true, true, true)
+		return
+	}
+
+	if !p.Stereo {
+		r.drawView(p, 0, 0, p.Width, Perspective(p.FOV, p.Width, p.Height, p.Near, p.Far))
+		return
+	}
+
+	half := p.Width / 2
+	eyeProjection := Perspective(p.FOV, half, p.Height, p.Near, p.Far)
+	r.drawView(p, -1, 0, half, eyeProjection)
+	r.drawView(p, 1, half, p.Width-half, eyeProjection)
+	gl.Viewport(0, 0, p.Width, p.Height)
+}
+
+// drawView draws the fractal into a full-height viewport starting x pixels
+// from the left and w wide, as seen from the given eye.
+func (r *Renderer) drawView(p Params, eye float32, x, w int32, proj mgl32.Mat4) {
+	gl.Viewport(x, 0, w, p.Height)
+
+	u := &r.uniforms
+	gl.Uniform1f(u.eye, eye)
+	gl.Uniform2f(u.viewportOrigin, float32(x), 0)
+	gl.Uniform2f(u.resolution, float32(w), float32(p.Height))
+	gl.UniformMatrix4fv(u.projection, 1, false, &proj[0])
+
+	r.quad.Draw()
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,166 @@
+// Package render holds the OpenGL side of drawing the fractal and the
+// plumbing shared with the HUD and post-processing passes: the fractal
+// renderer, shader programs, the full-screen quad and frame readback.
+package render
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"log"
+	"os"
+	"strings"
+)
+
+// Program is a linked vertex/fragment shader program. The locations of its
+// active uniforms are looked up once after linking rather than every frame.
+type Program struct {
+	ID       uint32
+	uniforms map[string]int32
+}
+
+// NewProgram compiles and links a vertex/fragment shader pair, cleaning up
+// any intermediate objects on failure.
+func NewProgram(vertexSource, fragmentSource string) (*Program, error) {
+	id, err := newProgram(vertexSource, fragmentSource)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{ID: id}
+	p.lookupUniforms()
+	return p, nil
+}
+
+// Reload rebuilds the program from new sources. On failure p is left
+// untouched so rendering can carry on with it; on success the old program
+// is deleted.
+func (p *Program) Reload(vertexSource, fragmentSource string) error {
+	id, err := newProgram(vertexSource, fragmentSource)
+	if err != nil {
+		return err
+	}
+
+	gl.DeleteProgram(p.ID)
+	p.ID = id
+	p.lookupUniforms()
+	return nil
+}
+
+// Uniform returns the location of the named uniform, or -1 if the program
+// doesn't use it. Setting location -1 is a no-op, the same as when
+// glGetUniformLocation fails.
+func (p *Program) Uniform(name string) int32 {
+	if location, ok := p.uniforms[name]; ok {
+		return location
+	}
+	return -1
+}
+
+func (p *Program) lookupUniforms() {
+	var count, maxLength int32
+	gl.GetProgramiv(p.ID, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(p.ID, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+
+	p.uniforms = make(map[string]int32, count)
+	buf := make([]uint8, maxLength+1)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveUniform(p.ID, i, int32(len(buf)), &length, &size, &xtype, &buf[0])
+
+		// Arrays are reported as name[0]; look them up by the bare name
+		name := strings.TrimSuffix(string(buf[:length]), "[0]")
+		p.uniforms[name] = gl.GetUniformLocation(p.ID, gl.Str(name+"\x00"))
+	}
+}
+
+// Use makes p the current program.
+func (p *Program) Use() {
+	gl.UseProgram(p.ID)
+}
+
+// Delete frees the program.
+func (p *Program) Delete() {
+	gl.DeleteProgram(p.ID)
+}
+
+func newProgram(vertexSource, fragmentSource string) (uint32, error) {
+	vertexShader, err := compileShader(vertexSource, gl.VERTEX_SHADER)
+	if err != nil {
+		return 0, fmt.Errorf("vertex shader: %v", err)
+	}
+
+	fragmentShader, err := compileShader(fragmentSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		gl.DeleteShader(vertexShader)
+		return 0, fmt.Errorf("fragment shader: %v", err)
+	}
+
+	return linkProgram(vertexShader, fragmentShader)
+}
+
+func compileShader(source string, shaderType uint32) (uint32, error) {
+	shader := gl.CreateShader(shaderType)
+	csources, free := gl.Strs(source)
+	gl.ShaderSource(shader, 1, csources, nil)
+	free()
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gl.DeleteShader(shader)
+		return 0, fmt.Errorf("failed to compile shader: %v", log)
+	}
+
+	return shader, nil
+}
+
+func linkProgram(vertexShader, fragmentShader uint32) (uint32, error) {
+	program := gl.CreateProgram()
+	gl.AttachShader(program, vertexShader)
+	gl.AttachShader(program, fragmentShader)
+	gl.LinkProgram(program)
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+		str := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(str))
+		gl.DeleteProgram(program)
+		return 0, fmt.Errorf("failed to link program: %v", str)
+	}
+
+	return program, nil
+}
+
+// LoadShaderFile reads GLSL source from disk and null-terminates it for
+// compilation.
+func LoadShaderFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\x00", nil
+}
+
+// ShaderSource prefers the copy of a shader on disk so it can be edited
+// without rebuilding, and falls back to the embedded version.
+func ShaderSource(path string, embedded string) string {
+	source, err := LoadShaderFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("failed to read shader, using embedded copy:", err)
+		}
+		return embedded + "\x00"
+	}
+	return source
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"m-box_explore/render"
+)
+
+const (
+	minResolutionScale      = 0.25
+	resolutionScaleStep     = 0.1
+	resolutionAdjustSeconds = 0.5 // minimum time between scale changes
+)
+
+// Adaptive resolution settings. resolutionScale is the current fraction of
+// the window size the scene is rendered at.
+var (
+	adaptiveResolution bool
+	targetFPS                  = 60.0
+	resolutionScale    float32 = 1.0
+)
+
+// resolutionScaler renders the scene into a reduced-size framebuffer and
+// stretches it over the window, picking the size from measured GPU time so
+// frames fit in the target frame time.
+type resolutionScaler struct {
+	copier       *render.Copy
+	fbo          uint32
+	colorTexture uint32
+	depthTexture uint32
+	width        int32
+	height       int32
+
+	query        uint32
+	queryPending bool
+	gpuTime      float64 // smoothed scene time in milliseconds
+	lastAdjust   float64
+}
+
+func newResolutionScaler(copier *render.Copy) *resolutionScaler {
+	r := &resolutionScaler{copier: copier}
+
+	gl.GenFramebuffers(1, &r.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, r.fbo)
+
+	gl.GenTextures(1, &r.colorTexture)
+	gl.BindTexture(gl.TEXTURE_2D, r.colorTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, r.colorTexture, 0)
+
+	gl.GenTextures(1, &r.depthTexture)
+	gl.BindTexture(gl.TEXTURE_2D, r.depthTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, r.depthTexture, 0)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	gl.GenQueries(1, &r.query)
+	return r
+}
+
+func (r *resolutionScaler) resize(w, h int32) {
+	if w == r.width && h == r.height {
+		return
+	}
+	r.width, r.height = w, h
+
+	gl.BindTexture(gl.TEXTURE_2D, r.colorTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, w, h, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+
+	gl.BindTexture(gl.TEXTURE_2D, r.depthTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT24, w, h, 0, gl.DEPTH_COMPONENT, gl.UNSIGNED_INT, nil)
+}
+
+// render runs drawScene at resolutionScale times the window size and
+// upscales the result into the window.
+func (r *resolutionScaler) render(drawScene func()) {
+	r.readGPUTime()
+	r.adjust()
+
+	w := max(int32(float32(fbWidth)*resolutionScale), 1)
+	h := max(int32(float32(fbHeight)*resolutionScale), 1)
+	r.resize(w, h)
+
+	// Render as if the window were the reduced size, for the scene and the
+	// passes around it
+	savedWidth, savedHeight := fbWidth, fbHeight
+	fbWidth, fbHeight = w, h
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, r.fbo)
+	gl.Viewport(0, 0, w, h)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	if !r.queryPending {
+		gl.BeginQuery(gl.TIME_ELAPSED, r.query)
+	}
+	drawScene()
+	if !r.queryPending {
+		gl.EndQuery(gl.TIME_ELAPSED)
+		r.queryPending = true
+	}
+
+	fbWidth, fbHeight = savedWidth, savedHeight
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+
+	// Drawn rather than blitted, as the window may be multisampled
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	r.copier.Draw(r.colorTexture)
+}
+
+// readGPUTime folds the last timer query into the smoothed GPU time once
+// its result is ready, without waiting on it.
+func (r *resolutionScaler) readGPUTime() {
+	if !r.queryPending {
+		return
+	}
+
+	var available int32
+	gl.GetQueryObjectiv(r.query, gl.QUERY_RESULT_AVAILABLE, &available)
+	if available == gl.FALSE {
+		return
+	}
+
+	var elapsed uint64
+	gl.GetQueryObjectui64v(r.query, gl.QUERY_RESULT, &elapsed)
+	r.queryPending = false
+
+	ms := float64(elapsed) / 1e6
+	if r.gpuTime == 0 {
+		r.gpuTime = ms
+	} else {
+		r.gpuTime += (ms - r.gpuTime) * 0.1
+	}
+}
+
+// adjust steps resolutionScale down when the scene runs over budget, and up
+// only when the larger size is predicted to still fit comfortably. The gap
+// between the two thresholds and the minimum interval between changes keep
+// it from flipping back and forth.
+func (r *resolutionScaler) adjust() {
+	now := glfw.GetTime()
+	if r.gpuTime == 0 || now-r.lastAdjust < resolutionAdjustSeconds {
+		return
+	}
+	budget := 1000 / targetFPS
+
+	// Cost scales with pixel count, so with the square of the scale
+	next := min(resolutionScale+resolutionScaleStep, 1)
+	grown := r.gpuTime * float64(next*next) / float64(resolutionScale*resolutionScale)
+
+	switch {
+	case r.gpuTime > budget && resolutionScale > minResolutionScale:
+		resolutionScale = max(resolutionScale-resolutionScaleStep, minResolutionScale)
+	case resolutionScale < 1 && grown < budget*0.8:
+		resolutionScale = next
+	default:
+		return
+	}
+	r.lastAdjust = now
+	r.gpuTime = 0 // measure afresh at the new size
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"m-box_explore/camera"
+	"m-box_explore/render"
+)
+
+// sceneParams gathers the current settings into the parameters the fractal
+// is drawn with, seen from cam and sized to the framebuffer.
+func sceneParams(cam *camera.Camera) render.Params {
+	return render.Params{
+		Position:      cam.Position,
+		Front:         cam.Front,
+		Up:            cam.Up,
+		Width:         fbWidth,
+		Height:        fbHeight,
+		FOV:           fov,
+		Near:          nearPlane,
+		Far:           maxDistance,
+		Zoom:          debugZoom,
+		Offset:        debugOffset,
+		Jitter:        jitter,
+		AASamples:     aaSamples,
+		Stereo:        stereo,
+		Anaglyph:      anaglyph,
+		AnaglyphSwap:  anaglyphSwap,
+		EyeSeparation: eyeSeparation,
+
+		Time:                 float32(animationTime),
+		FractalType:          fractalType,
+		Scale:                scale,
+		MaxIterations:        maxIterations,
+		LODFalloff:           lodFalloff,
+		FoldingLimit:         foldingLimit,
+		MinRadius:            minRadius,
+		FixedRadius:          fixedRadius,
+		EscapeRadius:         escapeRadius,
+		MengerIterations:     mengerIterations,
+		SierpinskiIterations: sierpinskiIterations,
+		FoldRotation:         foldRotation,
+		JuliaMode:            juliaMode,
+		JuliaC:               juliaC,
+		TestSphere:           testSphere,
+
+		MaxSteps:       maxSteps,
+		SurfaceEpsilon: surfaceEpsilon,
+		EpsilonFactor:  epsilonFactor,
+
+		LightPos:        lightPos,
+		LightDir:        lightDir,
+		ShadowSoftness:  shadowSoftness,
+		AOStrength:      aoStrength,
+		MaxReflections:  maxReflections,
+		SoftShadowSteps: softShadowSteps,
+		KeyLight:        keyLightEnabled,
+		FillLight:       fillLightEnabled,
+		FillLightDir:    fillLightDir,
+		FillLightColor:  fillLightColor,
+		Ambient:         ambientEnabled,
+		AmbientColor:    ambientColor,
+		FogColor:        fogColor,
+		FogDensity:      fogDensity,
+		Reflectivity:    reflectivity,
+		SkyTop:          skyTop,
+		SkyBottom:       skyBottom,
+		SunIntensity:    sunIntensity,
+		UseEnvironment:  environmentLoaded,
+		EnvironmentUnit: environmentTextureUnit,
+
+		ColorMode:      colorMode,
+		ColorCurve:     colorCurve,
+		ColorGamma:     colorGamma,
+		ColorScale:     colorScale,
+		HueShift:       hueShift,
+		PaletteUnit:    paletteTextureUnit,
+		ShowDepth:      showDepth,
+		StepHeatmap:    stepHeatmap,
+		Dither:         ditherStrength,
+		Gamma:          gamma,
+		Transparent:    transparent,
+		ContentScale:   contentScale,
+		Crosshair:      showCrosshair,
+		CrosshairColor: crosshairColor,
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"log"
+	"m-box_explore/render"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Timelapse settings, set with -timelapse and -timelapseDir. A negative
+// interval disables it and 0 saves every frame.
+var (
+	timelapseInterval = -1.0
+	timelapseDir      = "timelapse"
+	timelapseFrame    int
+	timelapseNext     float64
+)
+
+// screenshotRequested is set by the screenshot key and handled by
+// captureFrame once the next frame is drawn.
+var screenshotRequested bool
+
+// saveScreenshot reads back the frame drawn but not yet presented and writes
+// it to path as a PNG. The front buffer of a composited window can't be
+// read reliably, so this must run before SwapBuffers.
+func saveScreenshot(path string, width, height int) error {
+	gl.ReadBuffer(gl.BACK)
+	img := render.ReadPixels(width, height)
+	return render.WritePNG(path, img)
+}
+
+// screenshotPath names a screenshot in the working directory after the
+// current time.
+func screenshotPath() string {
+	return fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
+}
+
+// captureFrame saves a requested screenshot and the next timelapse frame,
+// if one is due, from the frame about to be presented.
+func captureFrame(now float64) {
+	if screenshotRequested {
+		screenshotRequested = false
+		path := screenshotPath()
+		if err := saveScreenshot(path, int(fbWidth), int(fbHeight)); err != nil {
+			log.Println("failed to save screenshot:", err)
+		} else {
+			fmt.Println("saved screenshot", path)
+		}
+	}
+	captureTimelapse(now)
+}
+
+// captureTimelapse saves the frame about to be presented to the next file
+// of the numbered sequence in timelapseDir, once the interval since the last
+// one has passed.
+func captureTimelapse(now float64) {
+	if timelapseInterval < 0 || now < timelapseNext {
+		return
+	}
+	timelapseNext = now + timelapseInterval
+
+	if timelapseFrame == 0 {
+		if err := os.MkdirAll(timelapseDir, 0755); err != nil {
+			log.Println("failed to create timelapse directory:", err)
+			timelapseInterval = -1
+			return
+		}
+	}
+
+	// Zero-padded so the frames sort in order
+	path := filepath.Join(timelapseDir, fmt.Sprintf("frame_%06d.png", timelapseFrame))
+	if err := saveScreenshot(path, int(fbWidth), int(fbHeight)); err != nil {
+		log.Println("failed to save timelapse frame:", err)
+		return
+	}
+	timelapseFrame++
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"os"
+)
+
+const sessionFile = "session.json"
+
+// noResume skips loading the saved session at startup.
+var noResume bool
+
+// Session is the explorer's state saved on exit and restored at the next
+// launch. Fields missing from an older file keep their defaults, and fields
+// this version doesn't know are ignored.
+type Session struct {
+	Position mgl32.Vec3 `json:"position"`
+	Yaw      float32    `json:"yaw"`
+	Pitch    float32    `json:"pitch"`
+	Roll     float32    `json:"roll"`
+	Walk     bool       `json:"walk"`
+
+	Scale                float32    `json:"scale"`
+	MaxIterations        int32      `json:"maxIterations"`
+	FractalType          int32      `json:"fractalType"`
+	FoldingLimit         float32    `json:"foldingLimit"`
+	MinRadius            float32    `json:"minRadius"`
+	FixedRadius          float32    `json:"fixedRadius"`
+	EscapeRadius         float32    `json:"escapeRadius"`
+	MengerIterations     int32      `json:"mengerIterations"`
+	SierpinskiIterations int32      `json:"sierpinskiIterations"`
+	FoldRotation         mgl32.Vec3 `json:"foldRotation"`
+	JuliaMode            bool       `json:"juliaMode"`
+	JuliaC               mgl32.Vec3 `json:"juliaC"`
+
+	ColorMode    int32   `json:"colorMode"`
+	ColorScale   float32 `json:"colorScale"`
+	ColorCurve   int32   `json:"colorCurve"`
+	ColorGamma   float32 `json:"colorGamma"`
+	HueShift     float32 `json:"hueShift"`
+	Gamma        float32 `json:"gamma"`
+	Reflectivity float32 `json:"reflectivity"`
+	FogDensity   float32 `json:"fogDensity"`
+
+	MaxSteps       int32   `json:"maxSteps"`
+	SurfaceEpsilon float32 `json:"surfaceEpsilon"`
+	EpsilonFactor  float32 `json:"epsilonFactor"`
+	LODFalloff     float32 `json:"lodFalloff"`
+	MaxDistance    float32 `json:"maxDistance"`
+	NearPlane      float32 `json:"nearPlane"`
+	FOV            float32 `json:"fov"`
+	AASamples      int32   `json:"aaSamples"`
+	VSync          bool    `json:"vsync"`
+
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// currentSession captures the current state. cam may be nil before the
+// camera exists, in which case the starting view is used.
+func currentSession(cam *camera.Camera) Session {
+	s := Session{
+		Position: startPos,
+		Yaw:      startYaw,
+		Pitch:    startPitch,
+
+		Scale:                scale,
+		MaxIterations:        maxIterations,
+		FractalType:          fractalType,
+		FoldingLimit:         foldingLimit,
+		MinRadius:            minRadius,
+		FixedRadius:          fixedRadius,
+		EscapeRadius:         escapeRadius,
+		MengerIterations:     mengerIterations,
+		SierpinskiIterations: sierpinskiIterations,
+		FoldRotation:         foldRotation,
+		JuliaMode:            juliaMode,
+		JuliaC:               juliaC,
+
+		ColorMode:    colorMode,
+		ColorScale:   colorScale,
+		ColorCurve:   colorCurve,
+		ColorGamma:   colorGamma,
+		HueShift:     hueShift,
+		Gamma:        gamma,
+		Reflectivity: reflectivity,
+		FogDensity:   fogDensity,
+
+		MaxSteps:       maxSteps,
+		SurfaceEpsilon: surfaceEpsilon,
+		EpsilonFactor:  epsilonFactor,
+		LODFalloff:     lodFalloff,
+		MaxDistance:    maxDistance,
+		NearPlane:      nearPlane,
+		FOV:            fov,
+		AASamples:      aaSamples,
+		VSync:          vsync,
+
+		Width:  width,
+		Height: height,
+	}
+	if cam != nil {
+		s.Position = cam.Position
+		s.Yaw, s.Pitch, s.Roll = cam.Yaw, cam.Pitch, cam.Roll
+		s.Walk = cam.Walk
+	}
+	return s
+}
+
+// saveSession writes the current state to path. The window size saved is
+// the windowed one, even from fullscreen.
+func saveSession(path string, window *glfw.Window, cam *camera.Camera) error {
+	s := currentSession(cam)
+	if window.GetMonitor() != nil {
+		s.Width, s.Height = windowedWidth, windowedHeight
+	} else {
+		s.Width, s.Height = window.GetSize()
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadSession restores the session saved at path, if there is one. Settings
+// given on the command line take precedence over the saved ones.
+func loadSession(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Start from the current values so missing fields keep them
+	s := currentSession(nil)
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["pos"] {
+		startPos = s.Position
+	}
+	if !set["yaw"] {
+		startYaw = s.Yaw
+	}
+	if !set["pitch"] {
+		startPitch = mgl32.Clamp(s.Pitch, -89, 89)
+	}
+	startRoll = s.Roll
+	startWalk = s.Walk
+
+	if !set["scale"] {
+		scale = mgl32.Clamp(s.Scale, minScale, maxScale)
+	}
+	// Hand-edited or stale values get the flags' ranges: zero iterations or
+	// steps draw nothing, and huge counts can hang the GPU
+	if !set["iterations"] && s.MaxIterations >= 1 && s.MaxIterations <= 1000 {
+		maxIterations = s.MaxIterations
+	}
+	if !set["fractal"] && s.FractalType >= 0 && s.FractalType < numFractalTypes {
+		fractalType = s.FractalType
+	}
+	if !set["foldingLimit"] {
+		foldingLimit = s.FoldingLimit
+	}
+	if !set["mengerIterations"] && s.MengerIterations >= 1 && s.MengerIterations <= 20 {
+		mengerIterations = s.MengerIterations
+	}
+	if !set["sierpinskiIterations"] && s.SierpinskiIterations >= 1 && s.SierpinskiIterations <= 30 {
+		sierpinskiIterations = s.SierpinskiIterations
+	}
+	if !set["foldRotation"] {
+		foldRotation = s.FoldRotation
+	}
+	// The sphere fold divides by both radii
+	if s.MinRadius > 0 && s.FixedRadius > 0 {
+		minRadius = s.MinRadius
+		fixedRadius = s.FixedRadius
+	}
+	escapeRadius = mgl32.Clamp(s.EscapeRadius, 1.5, 100)
+	juliaMode = s.JuliaMode
+	juliaC = s.JuliaC
+
+	colorMode = wrapIndex(s.ColorMode, numColorModes)
+	// The range the color scale keys keep it in
+	if s.ColorScale >= 0.01 && s.ColorScale <= 100 {
+		colorScale = s.ColorScale
+	}
+	colorCurve = wrapIndex(s.ColorCurve, numColorCurves)
+	colorGamma = mgl32.Clamp(s.ColorGamma, 0.1, 10)
+	hueShift = s.HueShift
+	if !set["gamma"] {
+		gamma = mgl32.Clamp(s.Gamma, 0.5, 4)
+	}
+	reflectivity = s.Reflectivity
+	fogDensity = s.FogDensity
+
+	if !set["maxSteps"] && s.MaxSteps >= 1 && s.MaxSteps <= 5000 {
+		maxSteps = s.MaxSteps
+	}
+	surfaceEpsilon = mgl32.Clamp(s.SurfaceEpsilon, 1e-6, 0.1)
+	epsilonFactor = s.EpsilonFactor
+	if !set["lodFalloff"] {
+		lodFalloff = max(s.LODFalloff, 0)
+	}
+	if !set["far"] {
+		maxDistance = s.MaxDistance
+	}
+	if !set["near"] {
+		nearPlane = s.NearPlane
+	}
+	if nearPlane <= 0 || maxDistance <= nearPlane {
+		nearPlane, maxDistance = 0.1, 100
+	}
+	if !set["fov"] {
+		fov = mgl32.Clamp(s.FOV, 20, 120)
+	}
+	// Only the counts the anti-aliasing key cycles through
+	switch s.AASamples {
+	case 1, 2, 4:
+		aaSamples = s.AASamples
+	}
+	vsync = s.VSync
+
+	if !set["width"] && s.Width > 0 {
+		width = s.Width
+	}
+	if !set["height"] && s.Height > 0 {
+		height = s.Height
+	}
+	return nil
+}
+
+// wrapIndex brings x into [0, n), for cycled settings such as colorMode
+// read back from a file, where a negative value must not survive.
+func wrapIndex(x, n int32) int32 {
+	return ((x % n) + n) % n
+}
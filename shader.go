@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"m-box_explore/render"
+)
+
+const (
+	vertexShaderPath   = "shaders/vertex.glsl"
+	fragmentShaderPath = "shaders/fragment.glsl"
+)
+
+// shaderSources are the fractal program's vertex and fragment shaders.
+type shaderSources struct {
+	vertex, fragment string
+}
+
+// readShaders reads the fractal shaders, preferring the files on disk over
+// the embedded copies.
+func readShaders() shaderSources {
+	return shaderSources{
+		vertex:   render.ShaderSource(vertexShaderPath, vertexShaderSource),
+		fragment: render.ShaderSource(fragmentShaderPath, fragmentShaderSource),
+	}
+}
+
+// reloadProgram rebuilds the fractal program from the shader files on disk,
+// keeping the current one if they don't compile.
+func reloadProgram(renderer *render.Renderer) {
+	src := readShaders()
+	err := renderer.Reload(src.vertex, src.fragment)
+	if err != nil {
+		log.Println("failed to reload shaders:", err)
+		return
+	}
+	fmt.Println("reloaded shaders")
+}
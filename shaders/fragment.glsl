@@ -0,0 +1,528 @@
+#version 330 core
+out vec4 FragColor;
+
+uniform vec3 cameraPos;
+uniform vec3 cameraFront;
+uniform vec3 cameraUp;
+uniform float scale;
+uniform int maxIterations;
+
+// Iteration level of detail: the Mandelbox and Mandelbulb iterate
+// maxIterations / (1 + lodFalloff * distance from the camera) times, but no
+// fewer than minLodIterations. 0 turns it off.
+uniform float lodFalloff;
+const int minLodIterations = 8;
+uniform vec2 resolution;
+uniform vec2 viewportOrigin; // lower-left corner of the viewport in window pixels
+uniform mat4 projection;
+
+// Seconds on the animation clock, which stops while animation is paused so
+// paused frames come out the same every time
+uniform float time;
+
+uniform float debugZoom; // narrows the rays around the view center, like a telescope
+uniform vec3 debugOffset; // added to the ray origins, moving the view without the camera
+
+// testSphere replaces the fractal with a unit sphere three units down -Z
+// from the origin, which should look round at any window shape.
+uniform bool testSphere;
+
+uniform int fractalType;
+
+uniform float foldingLimit;
+uniform float minRadius;
+uniform float fixedRadius;
+uniform int mengerIterations;
+uniform int sierpinskiIterations;
+
+// Rotation of the IFS fractals between folds, in radians about each axis
+uniform float rotX;
+uniform float rotY;
+uniform float rotZ;
+uniform float escapeRadius; // Mandelbox bailout; kept above 1 so log(r) stays positive
+
+// In Julia mode the Mandelbox adds a fixed constant each iteration instead of
+// the starting point.
+uniform bool juliaMode;
+uniform vec3 juliaC;
+
+uniform vec3 lightPos;
+uniform float shadowSoftness;
+uniform vec3 lightDir;
+uniform float aoStrength;
+
+// The main light above is the key light. A shadowless directional fill
+// light and a flat ambient term light what it can't reach.
+uniform bool keyLightEnabled;
+uniform bool fillLightEnabled;
+uniform vec3 fillLightDir;
+uniform vec3 fillLightColor;
+uniform bool ambientEnabled;
+uniform vec3 ambientColor;
+
+uniform int aaSamples;
+uniform vec2 jitter; // sub-pixel offset of this frame's samples, for accumulation
+
+uniform int maxSteps;
+uniform float surfaceEpsilon; // hit threshold; smaller is sharper but slower
+uniform float epsilonFactor;
+
+uniform vec3 fogColor;
+uniform float fogDensity;
+
+// Background for rays that miss, blended by ray height, plus a sun disk
+// toward lightDir scaled by sunIntensity (0 hides it)
+uniform vec3 skyTop;
+uniform vec3 skyBottom;
+uniform float sunIntensity;
+
+// When set, the background and escaped reflections sample this instead
+uniform bool useEnvironment;
+uniform samplerCube environment;
+
+uniform float reflectivity;
+uniform int maxReflections; // bounces traced; 0 for none
+uniform int softShadowSteps; // march steps for each shadow ray
+
+uniform int colorMode; // 0 = iteration count, 1 = orbit trap, 2 = surface normal, 3 = escape time
+uniform float colorScale; // palette repeats over the full range of march steps
+uniform int colorCurve;   // 0 = linear, 1 = step fraction raised to colorGamma
+uniform float colorGamma;
+uniform sampler1D palette;
+uniform float hueShift; // rotates the palette, in [0, 1)
+
+uniform bool showDepth; // debug view of the linear depth output
+uniform bool debugStepHeatmap; // debug view of the march steps each ray took
+uniform bool transparentBackground; // output alpha 0 where no surface was hit
+uniform float ditherStrength; // in 8-bit steps; 0 disables dithering
+uniform float gamma; // display gamma; shading is done in linear light
+
+// Side-by-side stereo: eye is -1 for the left view, 1 for the right and 0
+// for mono
+uniform float eye;
+uniform float eyeSeparation;
+
+uniform bool showCrosshair;
+uniform float contentScale; // framebuffer pixels per logical pixel
+uniform vec3 crosshairColor;
+
+uniform float maxDistance; // rays give up beyond this
+
+// iterationLimit is maxIterations after the level of detail for the point
+// being estimated, set by sceneDE.
+int iterationLimit;
+
+// trap receives the closest the orbit of z came to the origin, and escape
+// the iterations z took to pass the bailout radius, or iterationLimit if it
+// never did. escape is fractional: |z| grows by about |scale| per
+// iteration, so how far past the radius it landed says how much of the last
+// iteration was needed.
+float mandelboxDE(vec3 pos, out float trap, out float escape) {
+	vec3 z = pos;
+	float dr = 1.0;
+	float r = 0.0;
+	trap = 1e10;
+	escape = float(iterationLimit);
+
+	for (int i = 0; i < iterationLimit; i++) {
+		// Stop on overflow, keeping the last finite r for the estimate
+		float len = length(z);
+		if (isnan(len) || isinf(len)) break;
+		r = len;
+		if (r > escapeRadius) {
+			escape = float(i) - log(r / escapeRadius) / log(max(abs(scale), 1.01));
+			break;
+		}
+		trap = min(trap, r);
+
+		// Box fold
+		z = clamp(z, -foldingLimit, foldingLimit) * 2.0 - z;
+
+		// Sphere fold
+		if (r < minRadius) {
+			float f = (fixedRadius * fixedRadius) / (minRadius * minRadius);
+			z *= f;
+			dr *= f;
+		} else if (r < fixedRadius) {
+			float f = (fixedRadius * fixedRadius) / (r * r);
+			z *= f;
+			dr *= f;
+		}
+
+		z = z * scale + (juliaMode ? juliaC : pos);
+		dr = dr * abs(scale) + 1.0;
+	}
+
+	return 0.5 * log(r) * r / dr;
+}
+
+// Standard power-8 Mandelbulb in spherical coordinates. |z| grows as a
+// power here, so escape uses the usual log-log smoothing.
+float mandelbulbDE(vec3 pos, out float trap, out float escape) {
+	const float power = 8.0;
+	vec3 z = pos;
+	float dr = 1.0;
+	float r = 0.0;
+	trap = 1e10;
+	escape = float(iterationLimit);
+
+	for (int i = 0; i < iterationLimit; i++) {
+		r = length(z);
+		if (r > 2.0) {
+			escape = float(i) + 1.0 - log(log(r) / log(2.0)) / log(power);
+			break;
+		}
+		trap = min(trap, r);
+
+		float theta = acos(z.z / r) * power;
+		float phi = atan(z.y, z.x) * power;
+		dr = pow(r, power - 1.0) * power * dr + 1.0;
+
+		z = pow(r, power) * vec3(sin(theta) * cos(phi), sin(phi) * sin(theta), cos(theta)) + pos;
+	}
+
+	return 0.5 * log(r) * r / dr;
+}
+
+// foldRotation is built once per pixel from rotX/rotY/rotZ at the start of
+// main, rotating about x, then y, then z.
+mat3 foldRotation;
+
+mat3 rotationMatrix(vec3 angles) {
+	vec3 c = cos(angles);
+	vec3 s = sin(angles);
+	mat3 rx = mat3(1.0, 0.0, 0.0, 0.0, c.x, s.x, 0.0, -s.x, c.x);
+	mat3 ry = mat3(c.y, 0.0, -s.y, 0.0, 1.0, 0.0, s.y, 0.0, c.y);
+	mat3 rz = mat3(c.z, s.z, 0.0, -s.z, c.z, 0.0, 0.0, 0.0, 1.0);
+	return rz * ry * rx;
+}
+
+// Folded (Kaleidoscopic IFS) Menger sponge. Shares the Mandelbox controls:
+// the offset is foldingLimit, and the sponge scale is one more than scale so
+// the default of 2 gives the classic scale-3 sponge.
+float mengerDE(vec3 pos, out float trap) {
+	float s = scale + 1.0;
+	vec3 offset = vec3(foldingLimit);
+	vec3 z = pos;
+	float dr = 1.0;
+	trap = 1e10;
+
+	for (int i = 0; i < mengerIterations; i++) {
+		z = abs(z);
+		if (z.x < z.y) z.xy = z.yx;
+		if (z.x < z.z) z.xz = z.zx;
+		if (z.y < z.z) z.yz = z.zy;
+		// Rotations preserve distances, so only the scale touches dr
+		z = foldRotation * z;
+
+		z = z * s - offset * (s - 1.0);
+		dr *= s;
+		if (z.z < -0.5 * offset.z * (s - 1.0)) z.z += offset.z * (s - 1.0);
+		trap = min(trap, length(z));
+	}
+
+	vec3 d = abs(z) - vec3(1.0);
+	float box = min(max(d.x, max(d.y, d.z)), 0.0) + length(max(d, 0.0));
+	return box / dr;
+}
+
+// Folded (Kaleidoscopic IFS) Sierpinski tetrahedron. Each iteration mirrors
+// z across the tetrahedron's three symmetry planes, rotates it by
+// foldRotation and scales by 2 about the (1,1,1) vertex.
+float sierpinskiDE(vec3 pos, out float trap) {
+	const float s = 2.0;
+	vec3 vertex = vec3(1.0);
+	vec3 z = pos;
+	float dr = 1.0;
+	trap = 1e10;
+
+	for (int i = 0; i < sierpinskiIterations; i++) {
+		if (z.x + z.y < 0.0) z.xy = -z.yx;
+		if (z.x + z.z < 0.0) z.xz = -z.zx;
+		if (z.y + z.z < 0.0) z.yz = -z.zy;
+		z = foldRotation * z;
+
+		z = z * s - vertex * (s - 1.0);
+		dr *= s;
+		trap = min(trap, length(z));
+	}
+
+	return (length(z) - 1.0) / dr;
+}
+
+// escape is negative for the shapes that don't escape: the IFS fractals,
+// which always run a fixed number of folds, and the test sphere.
+float sceneDE(vec3 pos, out float trap, out float escape) {
+	escape = -1.0;
+	float dist = length(pos - cameraPos - debugOffset);
+	int lod = int(float(maxIterations) / (1.0 + lodFalloff * dist));
+	iterationLimit = max(lod, min(maxIterations, minLodIterations));
+	if (testSphere) {
+		trap = length(pos);
+		return length(pos - vec3(0.0, 0.0, -3.0)) - 1.0;
+	}
+	if (fractalType == 1) return mandelbulbDE(pos, trap, escape);
+	if (fractalType == 2) return mengerDE(pos, trap);
+	if (fractalType == 3) return sierpinskiDE(pos, trap);
+	return mandelboxDE(pos, trap, escape);
+}
+
+float sceneDE(vec3 pos, out float trap) {
+	float escape;
+	return sceneDE(pos, trap, escape);
+}
+
+float sceneDE(vec3 pos) {
+	float trap;
+	return sceneDE(pos, trap);
+}
+
+vec3 calcNormal(vec3 p) {
+	vec2 e = vec2(surfaceEpsilon, 0.0);
+	return normalize(vec3(
+		sceneDE(p + e.xyy) - sceneDE(p - e.xyy),
+		sceneDE(p + e.yxy) - sceneDE(p - e.yxy),
+		sceneDE(p + e.yyx) - sceneDE(p - e.yyx)
+	));
+}
+
+// Marches from ro toward the light, tracking the closest miss relative
+// to distance travelled; k controls how hard the penumbra is.
+float softShadow(vec3 ro, vec3 rd, float maxT, float k) {
+	float res = 1.0;
+	float t = 0.0;
+	for (int i = 0; i < softShadowSteps; i++) {
+		float h = sceneDE(ro + rd * t);
+		if (h < surfaceEpsilon) return 0.0;
+		if (t > 0.0) res = min(res, k * h / t);
+		t += h;
+		if (t >= maxT) break;
+	}
+	return clamp(res, 0.0, 1.0);
+}
+
+// Samples the DE at increasing distances along the normal; where the
+// surface is closer than the sample distance, the point is occluded.
+float ambientOcclusion(vec3 p, vec3 n) {
+	float occ = 0.0;
+	float weight = 1.0;
+	for (int i = 1; i <= 5; i++) {
+		float h = 0.02 * float(i);
+		occ += weight * (h - sceneDE(p + n * h));
+		weight *= 0.5;
+	}
+	return clamp(1.0 - 3.0 * occ, 0.0, 1.0);
+}
+
+// Looks x up in the palette gradient, rotated by hueShift and wrapping at
+// 1.0. The half-texel offset puts stop k exactly at x = k/N.
+vec3 paletteColor(float x) {
+	float n = float(textureSize(palette, 0));
+	return texture(palette, x + hueShift + 0.5 / n).rgb;
+}
+
+struct Hit {
+	bool hit;
+	float t;   // distance along the ray
+	int steps; // march steps taken
+};
+
+Hit march(vec3 ro, vec3 rd, int steps) {
+	float t = 0.0;
+	for (int i = 0; i < steps; i++) {
+		float d = sceneDE(ro + t * rd);
+		// Far hits cover less of the screen so need less precision
+		if (d < surfaceEpsilon * (1.0 + t * epsilonFactor)) {
+			return Hit(true, t, i);
+		}
+		t += d;
+		if (t > maxDistance) return Hit(false, t, i + 1);
+	}
+	return Hit(false, t, steps);
+}
+
+// stepHeat runs from blue for rays that took few steps through green to red
+// for ones that nearly used up maxSteps. Rays that ran out are white.
+vec3 stepHeat(int steps) {
+	if (steps >= maxSteps) return vec3(1.0);
+	float f = float(steps) / float(maxSteps);
+	if (f < 0.5) return mix(vec3(0.0, 0.0, 1.0), vec3(0.0, 1.0, 0.0), f * 2.0);
+	return mix(vec3(0.0, 1.0, 0.0), vec3(1.0, 0.0, 0.0), f * 2.0 - 1.0);
+}
+
+vec3 shade(vec3 p, vec3 n, int steps) {
+	// The normal as RGB, unlit so it shows calcNormal's output directly
+	if (colorMode == 2) return n * 0.5 + 0.5;
+
+	float trap, escape;
+	if (colorMode == 3) sceneDE(p, trap, escape);
+
+	vec3 color;
+	if (colorMode == 1) {
+		// Orbit trap: color by how close the orbit came to the origin
+		sceneDE(p, trap);
+		color = paletteColor(0.6 + trap * 0.5) * clamp(1.2 - trap * 0.5, 0.2, 1.0);
+	} else if (colorMode == 3 && escape >= 0.0) {
+		// Escape time: smooth, so no bands where the count steps up. The
+		// palette repeats every 20 iterations at a color scale of 1.
+		color = paletteColor(escape / 20.0 * colorScale);
+	} else {
+		// Normalize by the step budget so raising it doesn't darken everything
+		float f = float(steps) / float(maxSteps);
+		float x = colorCurve == 1 ? pow(f, colorGamma) : f;
+		color = paletteColor(x * colorScale) * (1.0 - f);
+	}
+
+	vec3 light = vec3(0.0);
+	if (keyLightEnabled) {
+		float diffuse = max(dot(n, normalize(lightDir)), 0.0);
+		// Start the shadow ray off the surface to avoid self-shadowing acne
+		vec3 toLight = lightPos - p;
+		light += diffuse * softShadow(p + n * surfaceEpsilon * 4.0, normalize(toLight), length(toLight), shadowSoftness);
+	}
+	if (fillLightEnabled) {
+		light += fillLightColor * max(dot(n, normalize(fillLightDir)), 0.0);
+	}
+	if (ambientEnabled) {
+		light += ambientColor;
+	}
+	light *= mix(1.0, ambientOcclusion(p, n), aoStrength);
+
+	return color * light;
+}
+
+vec3 sky(vec3 rd) {
+	vec3 color = mix(skyBottom, skyTop, clamp(rd.y * 0.5 + 0.5, 0.0, 1.0));
+	float sun = max(dot(rd, normalize(lightDir)), 0.0);
+	return color + vec3(1.0, 0.9, 0.7) * pow(sun, 256.0) * sunIntensity;
+}
+
+vec3 background(vec3 rd) {
+	if (useEnvironment) return texture(environment, rd).rgb;
+	return sky(rd);
+}
+
+vec3 applyFog(vec3 color, float t) {
+	return mix(color, fogColor, 1.0 - exp(-t * fogDensity));
+}
+
+// Returns the shaded color, and in alpha the hit distance as a fraction of
+// maxDistance (1.0 where nothing was hit).
+vec4 render(vec2 fragCoord) {
+	// uv runs from -1 to 1 vertically and is stretched horizontally by the
+	// viewport's own aspect ratio, so pixels stay square whatever aspect the
+	// projection was built for.
+	vec2 uv = ((fragCoord - viewportOrigin) / resolution.xy) * 2.0 - 1.0;
+	uv.x *= resolution.x / resolution.y;
+	uv /= debugZoom;
+
+	// projection[1][1] is 1/tan(fov/2). With uv divided by debugZoom above,
+	// the rays span tan(fov/2)/debugZoom, the narrowed field of view the
+	// rasterized overlays are projected with
+	float tanHalfFov = 1.0 / projection[1][1];
+	vec3 right = normalize(cross(cameraFront, cameraUp));
+	vec3 up = cross(right, cameraFront);
+	vec3 rayDir = normalize(cameraFront + (uv.x * right + uv.y * up) * tanHalfFov);
+
+	vec3 ro = cameraPos + debugOffset + right * eye * eyeSeparation * 0.5;
+
+	Hit h = march(ro, rayDir, maxSteps);
+	if (debugStepHeatmap) return vec4(stepHeat(h.steps), h.hit ? h.t / maxDistance : 1.0);
+	if (!h.hit) return vec4(background(rayDir), 1.0);
+
+	vec3 p = ro + h.t * rayDir;
+	vec3 n = calcNormal(p);
+	vec3 color = shade(p, n, h.steps);
+
+	// Each bounce keeps 1 - reflectivity of the surface it leaves and passes
+	// the rest on, with a smaller step budget since these are secondary rays
+	vec3 total = vec3(0.0);
+	float weight = 1.0;
+	vec3 dir = rayDir;
+	for (int b = 0; b < maxReflections && reflectivity > 0.0; b++) {
+		total += weight * (1.0 - reflectivity) * color;
+		weight *= reflectivity;
+
+		dir = reflect(dir, n);
+		vec3 origin = p + n * surfaceEpsilon * 4.0;
+		Hit rh = march(origin, dir, maxSteps / 4);
+		if (!rh.hit) {
+			color = background(dir);
+			break;
+		}
+		p = origin + rh.t * dir;
+		n = calcNormal(p);
+		color = applyFog(shade(p, n, rh.steps), rh.t);
+	}
+	total += weight * color;
+
+	return vec4(applyFog(total, h.t), h.t / maxDistance);
+}
+
+void main() {
+	foldRotation = rotationMatrix(vec3(rotX, rotY, rotZ));
+
+	// Average an aaSamples x aaSamples grid of rays across the pixel, keeping
+	// the nearest depth
+	vec3 color = vec3(0.0);
+	vec3 surfaceColor = vec3(0.0);
+	float hits = 0.0;
+	float depth = 1.0;
+	for (int x = 0; x < aaSamples; x++) {
+		for (int y = 0; y < aaSamples; y++) {
+			vec2 offset = (vec2(x, y) + 0.5) / float(aaSamples) - 0.5;
+			vec4 s = render(gl_FragCoord.xy + jitter + offset);
+			color += s.rgb;
+			depth = min(depth, s.a);
+			// Misses come back at depth 1
+			if (s.a < 1.0) {
+				surfaceColor += s.rgb;
+				hits += 1.0;
+			}
+		}
+	}
+	color /= float(aaSamples * aaSamples);
+
+	// With a transparent background, alpha is the fraction of rays that hit
+	// and the color is that of the surface alone, premultiplied by alpha once
+	// it is encoded below
+	float alpha = 1.0;
+	if (transparentBackground) {
+		alpha = hits / float(aaSamples * aaSamples);
+		color = hits > 0.0 ? surfaceColor / hits : vec3(0.0);
+	}
+
+	gl_FragDepth = depth;
+	// Encode for the display, leaving the debug views' colors as they are
+	if (!debugStepHeatmap) {
+		color = pow(max(color, 0.0), vec3(1.0 / gamma));
+	}
+	if (showDepth) {
+		color = vec3(depth);
+	}
+	// Screenshots read the frame into premultiplied images, compositors
+	// expect it, and the accumulation pass averages misses in as (0, 0)
+	color *= alpha;
+
+	if (showCrosshair) {
+		vec2 d = abs(gl_FragCoord.xy - viewportOrigin - resolution * 0.5);
+		vec2 arm = vec2(1.0, 8.0) * contentScale;
+		if ((d.x < arm.x && d.y < arm.y) || (d.y < arm.x && d.x < arm.y)) {
+			color = crosshairColor;
+			alpha = 1.0;
+		}
+	}
+	// Interleaved gradient noise: a fixed per-pixel offset of under one
+	// 8-bit step that breaks up banding in smooth gradients. Fully
+	// transparent pixels are left clear.
+	if (alpha > 0.0) {
+		float noise = fract(52.9829189 * fract(dot(gl_FragCoord.xy, vec2(0.06711056, 0.00583715))));
+		color += (noise - 0.5) * ditherStrength / 255.0;
+	}
+	// A premultiplied channel can't exceed alpha
+	if (transparentBackground) {
+		color = clamp(color, 0.0, alpha);
+	}
+
+	FragColor = vec4(color, alpha);
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"m-box_explore/camera"
+	"m-box_explore/render"
+	"math"
+)
+
+var showBounds bool
+
+var (
+	boundsVertexShaderSource = `
+		#version 330 core
+		layout (location = 0) in vec3 aPos;
+		uniform mat4 mvp;
+		void main() {
+			gl_Position = mvp * vec4(aPos, 1.0);
+		}
+	` + "\x00"
+
+	boundsFragmentShaderSource = `
+		#version 330 core
+		out vec4 FragColor;
+		uniform vec3 color;
+		void main() {
+			FragColor = vec4(color, 1.0);
+		}
+	` + "\x00"
+)
+
+// boundsBox draws the edges of the cube outside which the current fractal's
+// distance estimator bails out, as a check on the camera and projection.
+type boundsBox struct {
+	program *render.Program
+	vao     uint32
+	vbo     uint32
+}
+
+func newBoundsBox() (*boundsBox, error) {
+	program, err := render.NewProgram(boundsVertexShaderSource, boundsFragmentShaderSource)
+	if err != nil {
+		return nil, err
+	}
+
+	// The 12 edges of the unit cube, each as a pair of corners
+	var vertices []float32
+	for axis := 0; axis < 3; axis++ {
+		for _, a := range []float32{-1, 1} {
+			for _, b := range []float32{-1, 1} {
+				from, to := mgl32.Vec3{}, mgl32.Vec3{}
+				from[axis], to[axis] = -1, 1
+				from[(axis+1)%3], to[(axis+1)%3] = a, a
+				from[(axis+2)%3], to[(axis+2)%3] = b, b
+				vertices = append(vertices, from[:]...)
+				vertices = append(vertices, to[:]...)
+			}
+		}
+	}
+
+	w := &boundsBox{program: program}
+	gl.GenVertexArrays(1, &w.vao)
+	gl.BindVertexArray(w.vao)
+
+	gl.GenBuffers(1, &w.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, w.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+
+	return w, nil
+}
+
+// boundsRadius is the half-size of the region the current fractal lives in:
+// the bailout radius of the iterated fractals and the unit cube around the
+// sponge and the tetrahedron.
+func boundsRadius() float32 {
+	switch fractalType {
+	case 1:
+		return 2
+	case 2, 3:
+		return 1
+	default:
+		return escapeRadius
+	}
+}
+
+// draw overlays the box on the full window from cam's point of view, as
+// zoomed and offset for debugging.
+func (w *boundsBox) draw(cam *camera.Camera) {
+	r := boundsRadius()
+	proj := render.Perspective(viewFOV(), fbWidth, fbHeight, nearPlane, maxDistance)
+	mvp := proj.Mul4(eyeViewMatrix(cam)).Mul4(mgl32.Scale3D(r, r, r))
+
+	gl.Viewport(0, 0, fbWidth, fbHeight)
+	w.program.Use()
+	gl.UniformMatrix4fv(w.program.Uniform("mvp"), 1, false, &mvp[0])
+	gl.Uniform3f(w.program.Uniform("color"), 0, 1, 0)
+
+	gl.BindVertexArray(w.vao)
+	gl.DrawArrays(gl.LINES, 0, 24)
+}
+
+// tanHalfViewFOV is the tangent of half the vertical field of view the scene
+// is drawn with. The shader narrows fov by dividing its ray offsets by
+// debugZoom.
+func tanHalfViewFOV() float32 {
+	return float32(math.Tan(float64(mgl32.DegToRad(fov))/2)) / debugZoom
+}
+
+// viewFOV is the vertical field of view the scene is drawn with, in degrees.
+func viewFOV() float32 {
+	return mgl32.RadToDeg(2 * float32(math.Atan(float64(tanHalfViewFOV()))))
+}
+
+// eyeViewMatrix is the view transform from the eye the scene is drawn from,
+// debugOffset away from cam.
+func eyeViewMatrix(cam *camera.Camera) mgl32.Mat4 {
+	eye := cam.Position.Add(debugOffset)
+	return mgl32.LookAtV(eye, eye.Add(cam.Front), cam.Up)
+}